@@ -1,9 +1,76 @@
 package kyugo
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"sync"
 )
 
+// HandlerAdapter converts a handler value of a type it understands into an
+// http.HandlerFunc. It returns false when h is not such a type so the next
+// adapter can be tried.
+type HandlerAdapter func(h interface{}) (http.HandlerFunc, bool)
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   []HandlerAdapter
+)
+
+// RegisterHandlerAdapter adds an adapter consulted, in registration order,
+// for handler values that none of the built-in shapes match. Register
+// adapters before declaring routes.
+func RegisterHandlerAdapter(a HandlerAdapter) {
+	if a == nil {
+		return
+	}
+	adaptersMu.Lock()
+	adapters = append(adapters, a)
+	adaptersMu.Unlock()
+}
+
+// handlerToHTTP converts a handler provided by the caller to an
+// http.HandlerFunc. Supported types:
+//   - http.HandlerFunc and func(http.ResponseWriter, *http.Request)
+//   - func(*Response, *Request)
+//   - func(*Response, *Request) error
+//   - func(context.Context, *Request) (any, error)
+//   - *TypedHandler (see Handle) and any other http.Handler
+//   - anything accepted by an adapter added with RegisterHandlerAdapter
+//
+// Any other value is reported as an error naming its type.
+func handlerToHTTP(h interface{}) (http.HandlerFunc, error) {
+	switch v := h.(type) {
+	case nil:
+		return nil, fmt.Errorf("nil handler")
+	case http.HandlerFunc:
+		return v, nil
+	case func(http.ResponseWriter, *http.Request):
+		return v, nil
+	case func(*Response, *Request):
+		return Adapt(v), nil
+	case func(*Response, *Request) error:
+		return AdaptError(v), nil
+	case func(context.Context, *Request) (any, error):
+		return AdaptContext(v), nil
+	case http.Handler:
+		return v.ServeHTTP, nil
+	}
+
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+	for _, a := range adapters {
+		if hf, ok := a(h); ok && hf != nil {
+			return hf, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported handler type %T", h)
+}
+
 // Adapt converts a handler function that accepts our wrapper types into a
 // standard http.HandlerFunc. The input `h` is expected to be a bound
 // method value or function with signature func(*response.Response, *request.Request).
@@ -14,3 +81,155 @@ func Adapt(h func(*Response, *Request)) http.HandlerFunc {
 		h(resp, req)
 	}
 }
+
+// AdaptError converts a handler that returns an error into an
+// http.HandlerFunc. A non-nil error is rendered by the router's error
+// handler (see Router.ErrorHandler and DefaultErrorHandler).
+func AdaptError(h func(*Response, *Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(NewResponse(w, r), NewRequest(r)); err != nil {
+			handleError(w, r, err)
+		}
+	}
+}
+
+// AdaptContext converts a handler that receives the request context and
+// returns a result into an http.HandlerFunc. The result is written as a
+// success envelope and errors are rendered by the router's error handler.
+func AdaptContext(h func(context.Context, *Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), httpRequestKey, r)
+		res, err := h(ctx, NewRequest(r))
+		writeResult(w, r, res, err)
+	}
+}
+
+// writeResult writes the outcome of a value-returning handler.
+func writeResult(w http.ResponseWriter, r *http.Request, res any, err error) {
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	status := http.StatusOK
+	if sc, ok := res.(StatusCoder); ok && sc.StatusCode() != 0 {
+		status = sc.StatusCode()
+	}
+	WriteSuccess(w, r, status, "", res)
+}
+
+// StatusCoder can be implemented by typed handler results to choose the
+// status reported in the success envelope (200 by default).
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// TypedHandler is the handler produced by Handle. Besides serving requests
+// it records the request and response types so route registration can
+// expose them through Router.Routes.
+type TypedHandler struct {
+	Req   reflect.Type
+	Res   reflect.Type
+	name  string
+	serve http.HandlerFunc
+}
+
+// ServeHTTP implements http.Handler.
+func (h *TypedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r)
+}
+
+// Handle adapts a typed handler into a route handler. For each request a
+// fresh Req is bound from the body, decoded by its Content-Type, then from path params (`path`
+// tag) and the query string (`query` tag), and validated. On success the
+// result is written as a SuccessEnvelope; a returned error is rendered by
+// the router's error handler. Use RequestFromContext inside fn to reach
+// the request.
+//
+//	r.Post("/products", kyugo.Handle(ctrl.Create))
+func Handle[Req, Res any](fn func(ctx context.Context, req Req) (Res, error)) *TypedHandler {
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	resType := reflect.TypeOf((*Res)(nil)).Elem()
+
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		ptr := newDTO(reqType)
+		if !bindRequest(w, r, ptr) {
+			return
+		}
+		var req Req
+		if reqType.Kind() == reflect.Ptr {
+			req = ptr.(Req)
+		} else {
+			req = reflect.ValueOf(ptr).Elem().Interface().(Req)
+		}
+
+		ctx := context.WithValue(r.Context(), httpRequestKey, r)
+		res, err := fn(ctx, req)
+		writeResult(w, r, res, err)
+	}
+
+	return &TypedHandler{Req: reqType, Res: resType, name: funcName(fn), serve: serve}
+}
+
+// RequestFromContext returns the request wrapper stored by Handle and
+// AdaptContext in the context passed to their handlers, or nil.
+func RequestFromContext(ctx context.Context) *Request {
+	if ctx == nil {
+		return nil
+	}
+	r, ok := ctx.Value(httpRequestKey).(*http.Request)
+	if !ok {
+		return nil
+	}
+	return NewRequest(r)
+}
+
+// bindRequest fills ptr from the body (decoded by Content-Type), the path params and the query
+// string, then validates it. Path and query fields must carry an explicit
+// `path` or `query` tag. It returns false after writing an error response.
+func bindRequest(w http.ResponseWriter, r *http.Request, ptr interface{}) bool {
+	if r.Body != nil && r.Body != http.NoBody {
+		b, ok := readBody(w, r)
+		if !ok {
+			return false
+		}
+		if len(bytes.TrimSpace(b)) > 0 {
+			if c, _ := requestCodec(r); isJSONCodec(c) && isStrict(r) {
+				fields, err := checkStrictJSON(b, reflect.TypeOf(ptr))
+				if err != nil {
+					writeInvalidBody(w, r)
+					return false
+				}
+				if len(fields) > 0 {
+					writeValidationFailed(w, r, fields)
+					return false
+				}
+			}
+			if err := decodeBody(r, b, ptr); err != nil {
+				if errors.Is(err, ErrUnsupportedMediaType) {
+					writeUnsupportedMediaType(w, r, supportedMediaTypes())
+				} else {
+					writeInvalidBody(w, r)
+				}
+				return false
+			}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	if reflect.TypeOf(ptr).Elem().Kind() != reflect.Struct {
+		return true
+	}
+	if fields := bindTagged(pathValues(r), "path", ptr); len(fields) > 0 {
+		writeInvalidParams(w, r, fields)
+		return false
+	}
+	if fields := bindTagged(r.URL.Query(), "query", ptr); len(fields) > 0 {
+		writeValidationFailed(w, r, fields)
+		return false
+	}
+	if err := Validate(ptr); err != nil {
+		writeValidationFailed(w, r, formatValidationErrors(err, ptr, "json", "path", "query"))
+		return false
+	}
+	return true
+}
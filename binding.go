@@ -0,0 +1,226 @@
+package kyugo
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerTy = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// timeLayouts lists the layouts tried, in order, when binding a string
+// value into a time.Time field.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// newDTO returns a pointer to a fresh zero value of the type described by
+// t. Pointer types are dereferenced once so both `&Dto{}` and `Dto{}`
+// example values produce a `*Dto`.
+func newDTO(t reflect.Type) interface{} {
+	if t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface()
+	}
+	return reflect.New(t).Interface()
+}
+
+// fieldKey resolves the lookup key for a struct field using the provided
+// tags in order, falling back to the json tag and finally the lower-cased
+// field name. The returned bool is false when the field should be skipped.
+func fieldKey(sf reflect.StructField, tags ...string) (string, bool) {
+	for _, t := range append(tags, "json") {
+		if name, ok := tagName(sf, t); ok {
+			return name, name != "-"
+		}
+	}
+	return strings.ToLower(sf.Name), true
+}
+
+// taggedKey is like fieldKey but only honours tag itself; fields without
+// it are skipped. It is used when one struct mixes body, path and query
+// fields so that, for example, body fields are never read from the query.
+func taggedKey(sf reflect.StructField, tag string) (string, bool) {
+	name, ok := tagName(sf, tag)
+	return name, ok && name != "-"
+}
+
+// tagName returns the name part of tag on sf, when present and non-empty.
+func tagName(sf reflect.StructField, tag string) (string, bool) {
+	v, ok := sf.Tag.Lookup(tag)
+	if !ok {
+		return "", false
+	}
+	name := strings.Split(v, ",")[0]
+	return name, name != ""
+}
+
+// pathValues returns the chi URL params of the matched route as url.Values.
+func pathValues(r *http.Request) url.Values {
+	vals := url.Values{}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, k := range rctx.URLParams.Keys {
+			if i < len(rctx.URLParams.Values) {
+				vals.Set(k, rctx.URLParams.Values[i])
+			}
+		}
+	}
+	return vals
+}
+
+// bindValues decodes vals into the struct pointed to by dst. Struct fields
+// are matched by `tag` (for example "query" or "path"). Slice fields collect
+// every value of a repeated key. Conversion failures are returned as field
+// errors rather than aborting so callers can report all of them at once.
+func bindValues(vals url.Values, tag string, dst interface{}) []FieldError {
+	return bindWith(vals, dst, func(sf reflect.StructField) (string, bool) {
+		return fieldKey(sf, tag)
+	})
+}
+
+// bindTagged is like bindValues but only fills fields that explicitly carry
+// tag.
+func bindTagged(vals url.Values, tag string, dst interface{}) []FieldError {
+	return bindWith(vals, dst, func(sf reflect.StructField) (string, bool) {
+		return taggedKey(sf, tag)
+	})
+}
+
+func bindWith(vals url.Values, dst interface{}, key func(reflect.StructField) (string, bool)) []FieldError {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return []FieldError{{Code: "INVALID_TYPE", Message: "destination must be a non-nil pointer"}}
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return []FieldError{{Code: "INVALID_TYPE", Message: "destination must point to a struct"}}
+	}
+	return bindStruct(vals, rv, key)
+}
+
+func bindStruct(vals url.Values, rv reflect.Value, fieldKey func(reflect.StructField) (string, bool)) []FieldError {
+	var out []FieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		// embedded structs contribute their fields to the parent
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			out = append(out, bindStruct(vals, fv, fieldKey)...)
+			continue
+		}
+		key, ok := fieldKey(sf)
+		if !ok {
+			continue
+		}
+		raw, present := vals[key]
+		if !present || len(raw) == 0 {
+			continue
+		}
+		if err := setField(fv, raw); err != nil {
+			out = append(out, FieldError{
+				Field:   key,
+				Code:    "INVALID_TYPE",
+				Message: fmt.Sprintf("invalid value for %s: %v", key, err),
+			})
+		}
+	}
+	return out
+}
+
+// setField assigns raw to fv, converting according to fv's type. Slices
+// receive every element of raw; scalar fields use the first value.
+func setField(fv reflect.Value, raw []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(fv.Type(), len(raw), len(raw))
+		for i, v := range raw {
+			if err := setScalar(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+	return setScalar(fv, raw[0])
+}
+
+func setScalar(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Ptr {
+		nv := reflect.New(fv.Type().Elem())
+		if err := setScalar(nv.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(nv)
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerTy) && fv.Type() != timeType {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch fv.Type() {
+	case timeType:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				fv.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot parse %q as time", s)
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
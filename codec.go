@@ -0,0 +1,433 @@
+package kyugo
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes response envelopes and decodes request bodies for one
+// media type.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Built-in media types.
+const (
+	MediaTypeJSON    = "application/json"
+	MediaTypeXML     = "application/xml"
+	MediaTypeMsgPack = "application/msgpack"
+	MediaTypeCBOR    = "application/cbor"
+)
+
+// ErrUnsupportedMediaType is returned by Request.Bind when no codec is
+// registered for the request Content-Type.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+var (
+	codecsMu   sync.RWMutex
+	codecs     = map[string]Codec{}
+	codecOrder []string
+)
+
+func init() {
+	RegisterCodec(MediaTypeJSON, jsonCodec{})
+	RegisterCodec(MediaTypeXML, xmlCodec{})
+	RegisterCodec("text/xml", xmlCodec{})
+	RegisterCodec(MediaTypeMsgPack, msgpackCodec{})
+	RegisterCodec("application/x-msgpack", msgpackCodec{})
+	RegisterCodec("application/vnd.msgpack", msgpackCodec{})
+	RegisterCodec(MediaTypeCBOR, cborCodec{})
+}
+
+// RegisterCodec registers c for mediaType, replacing any previous codec.
+// A nil codec removes the media type. Wildcard Accept ranges resolve to
+// codecs in registration order, so JSON stays the default.
+func RegisterCodec(mediaType string, c Codec) {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" {
+		return
+	}
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	_, exists := codecs[mediaType]
+	if c == nil {
+		delete(codecs, mediaType)
+		for i, mt := range codecOrder {
+			if mt == mediaType {
+				codecOrder = append(codecOrder[:i], codecOrder[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	codecs[mediaType] = c
+	if !exists {
+		codecOrder = append(codecOrder, mediaType)
+	}
+}
+
+// CodecFor returns the codec registered for mediaType. Parameters are
+// ignored and the `+json` structured syntax suffix resolves to JSON, so
+// "application/vnd.app.v2+json; charset=utf-8" finds the JSON codec. Other
+// suffixes are not mapped: `application/xhtml+xml` is not XML data.
+func CodecFor(mediaType string) (Codec, bool) {
+	_, c, ok := lookupCodec(mediaType)
+	return c, ok
+}
+
+// lookupCodec returns the registered media type serving mediaType and its
+// codec.
+func lookupCodec(mediaType string) (string, Codec, bool) {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return "", nil, false
+	}
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	if c, ok := codecs[mt]; ok {
+		return mt, c, true
+	}
+	if strings.HasSuffix(mt, "+json") {
+		if c, ok := codecs[MediaTypeJSON]; ok {
+			return MediaTypeJSON, c, true
+		}
+	}
+	return "", nil, false
+}
+
+// requestCodec returns the codec for the request Content-Type. Requests
+// without a Content-Type are decoded as JSON.
+func requestCodec(r *http.Request) (Codec, bool) {
+	ct := r.Header.Get("Content-Type")
+	if strings.TrimSpace(ct) == "" {
+		return jsonCodec{}, true
+	}
+	return CodecFor(ct)
+}
+
+// decodeBody decodes b into v using the codec for the request Content-Type.
+func decodeBody(r *http.Request, b []byte, v interface{}) error {
+	c, ok := requestCodec(r)
+	if !ok {
+		return ErrUnsupportedMediaType
+	}
+	return c.Unmarshal(b, v)
+}
+
+type acceptRange struct {
+	typ string
+	q   float64
+}
+
+// parseAccept returns the media ranges of an Accept header ordered by
+// quality, then by specificity.
+func parseAccept(header string) []acceptRange {
+	var out []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				q = f
+			}
+		}
+		out = append(out, acceptRange{typ: mt, q: q})
+	}
+	specificity := func(t string) int {
+		switch {
+		case t == "*/*":
+			return 0
+		case strings.HasSuffix(t, "/*"):
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].q != out[j].q {
+			return out[i].q > out[j].q
+		}
+		return specificity(out[i].typ) > specificity(out[j].typ)
+	})
+	return out
+}
+
+// acceptQuality returns the quality ranges give mt, taken from the most
+// specific range matching it, and whether that range names mt exactly. It
+// is 0 when no range matches.
+func acceptQuality(ranges []acceptRange, mt string) (float64, bool) {
+	q, best := 0.0, -1
+	for _, ar := range ranges {
+		spec := -1
+		switch {
+		case ar.typ == mt:
+			spec = 2
+		case strings.HasSuffix(ar.typ, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(ar.typ, "*")):
+			spec = 1
+		case ar.typ == "*/*":
+			spec = 0
+		}
+		if spec > best {
+			q, best = ar.q, spec
+		}
+	}
+	return q, best == 2
+}
+
+// negotiate picks the response media type and codec for r from its Accept
+// header. JSON is used when the header is absent, and for browser
+// navigations (Accept listing text/html) as long as JSON is acceptable;
+// ok is false when no registered codec is acceptable.
+func negotiate(r *http.Request) (string, Codec, bool) {
+	header := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		return MediaTypeJSON, jsonCodec{}, true
+	}
+	ranges := parseAccept(header)
+	excluded := map[string]bool{}
+	html := false
+	for _, ar := range ranges {
+		if ar.q <= 0 {
+			excluded[ar.typ] = true
+		} else if ar.typ == "text/html" {
+			html = true
+		}
+	}
+	if html && !excluded[MediaTypeJSON] {
+		for _, ar := range ranges {
+			if ar.q > 0 && (ar.typ == MediaTypeJSON || ar.typ == "application/*" || ar.typ == "*/*") {
+				if c, ok := CodecFor(MediaTypeJSON); ok {
+					return MediaTypeJSON, c, true
+				}
+			}
+		}
+	}
+	for _, ar := range ranges {
+		if ar.q <= 0 {
+			continue
+		}
+		if !strings.HasSuffix(ar.typ, "/*") {
+			if mt, c, ok := lookupCodec(ar.typ); ok && !excluded[mt] {
+				return mt, c, true
+			}
+			continue
+		}
+		prefix := strings.TrimSuffix(ar.typ, "*")
+		codecsMu.RLock()
+		for _, mt := range codecOrder {
+			if (ar.typ == "*/*" || strings.HasPrefix(mt, prefix)) && !excluded[mt] {
+				c := codecs[mt]
+				codecsMu.RUnlock()
+				return mt, c, true
+			}
+		}
+		codecsMu.RUnlock()
+	}
+	return "", nil, false
+}
+
+// supportedMediaTypes lists the registered media types in preference order.
+func supportedMediaTypes() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	return append([]string(nil), codecOrder...)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// xmlCodec renders the data and meta of envelopes from their JSON form, so
+// DTOs keep the names of their json tags and need no xml tags: objects
+// become elements named by their keys (an `entry` element with a `key`
+// attribute when the key is not a valid XML name) and array items are
+// wrapped in `item` elements.
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	switch env := v.(type) {
+	case *SuccessEnvelope:
+		v = xmlEnvelope(*env)
+	case SuccessEnvelope:
+		v = xmlEnvelope(env)
+	case *ErrorEnvelope:
+		v = xmlErrorEnvelope(*env)
+	case ErrorEnvelope:
+		v = xmlErrorEnvelope(env)
+	}
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+func xmlEnvelope(env SuccessEnvelope) SuccessEnvelope {
+	env.Data = xmlJSON{env.Data}
+	if env.Meta != nil {
+		env.Meta = xmlJSON{env.Meta}
+	}
+	return env
+}
+
+func xmlErrorEnvelope(env ErrorEnvelope) ErrorEnvelope {
+	if env.Error.Meta != nil {
+		env.Error.Meta = xmlJSON{env.Error.Meta}
+	}
+	return env
+}
+
+// xmlJSON encodes its value as XML following the value's JSON encoding.
+type xmlJSON struct{ v interface{} }
+
+func (x xmlJSON) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	b, err := json.Marshal(x.v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return encodeJSONAsXML(e, dec, start)
+}
+
+// encodeJSONAsXML writes the next JSON value of dec as the element start.
+func encodeJSONAsXML(e *xml.Encoder, dec *json.Decoder, start xml.StartElement) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for dec.More() {
+			child := xml.StartElement{Name: xml.Name{Local: "item"}}
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = xmlElementFor(key.(string))
+			}
+			if err := encodeJSONAsXML(e, dec, child); err != nil {
+				return err
+			}
+		}
+		// closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	case nil:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	case json.Number:
+		return e.EncodeElement(t.String(), start)
+	default:
+		return e.EncodeElement(t, start)
+	}
+}
+
+// xmlElementFor returns the element for the object key name.
+func xmlElementFor(name string) xml.StartElement {
+	valid := name != "" && !strings.HasPrefix(strings.ToLower(name), "xml")
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			valid = false
+		}
+	}
+	if valid {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+	}
+}
+
+// Unmarshal decodes data into v. encoding/xml cannot fill an empty
+// interface, so for *interface{} it only checks that data is well formed.
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	if _, ok := v.(*interface{}); !ok {
+		return xml.Unmarshal(data, v)
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	root := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if !root {
+				return errors.New("xml: no root element")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			root = true
+		}
+	}
+}
+
+// msgpackCodec honours `json` struct tags so DTOs need no extra tags.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// cborCodec relies on fxamacker/cbor falling back to `json` struct tags.
+type cborCodec struct{}
+
+func (cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
@@ -0,0 +1,134 @@
+package kyugo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/vmihailenco/msgpack/v5"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+
+func negotiated(accept string) string {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	mt, _, ok := negotiate(r)
+	if !ok {
+		return ""
+	}
+	return mt
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                                 MediaTypeJSON,
+		"*/*":                              MediaTypeJSON,
+		browserAccept:                      MediaTypeJSON,
+		"application/xml":                  MediaTypeXML,
+		"application/xhtml+xml":            "",
+		"application/vnd.app.v2+json":      MediaTypeJSON,
+		"application/msgpack, */*;q=0.1":   MediaTypeMsgPack,
+		"application/json;q=0, text/xml":   "text/xml",
+		"text/html, application/xml;q=0.5": MediaTypeXML,
+		"image/png":                        "",
+	}
+	for accept, want := range cases {
+		if got := negotiated(accept); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestWriteSuccessBrowserGetsJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", browserAccept)
+	WriteSuccess(w, r, http.StatusOK, "ok", map[string]int{"a": 1})
+	if ct := w.Header().Get("Content-Type"); ct != MediaTypeJSON {
+		t.Fatalf("Content-Type = %q, want JSON", ct)
+	}
+}
+
+type xmlItem struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name,omitempty"`
+	Tags []string `json:"tags"`
+}
+
+func TestXMLEnvelopeUsesJSONNames(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", MediaTypeXML)
+	WriteSuccess(w, r, http.StatusOK, "ok", []xmlItem{{ID: 1, Name: "a", Tags: []string{"x", "y"}}, {ID: 2}})
+
+	if ct := w.Header().Get("Content-Type"); ct != MediaTypeXML {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	want := `<data><item><id>1</id><name>a</name><tags><item>x</item><item>y</item></tags></item><item><id>2</id><tags></tags></item></data>`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Fatalf("body = %s\nwant it to contain %s", w.Body.String(), want)
+	}
+}
+
+func TestXMLEncodesMapsAndOddKeys(t *testing.T) {
+	b, err := xmlCodec{}.Marshal(SuccessEnvelope{Status: "success", Data: map[string]interface{}{"1st": true, "ok": nil}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<data><entry key="1st">true</entry><ok></ok></data>`
+	if !strings.Contains(string(b), want) {
+		t.Fatalf("got %s", b)
+	}
+}
+
+// noMsgpack encodes as JSON but refuses MessagePack.
+type noMsgpack struct{}
+
+func (noMsgpack) MarshalJSON() ([]byte, error) { return []byte(`"json only"`), nil }
+
+func (noMsgpack) EncodeMsgpack(*msgpack.Encoder) error { return errors.New("no msgpack form") }
+
+func TestEncodeFailureFallsBackOnlyToAcceptableJSON(t *testing.T) {
+	var out syncBuffer
+	logger.SetStd(&logger.Logger{Z: zerolog.New(&out)})
+	defer logger.SetStd(logger.NewNop())
+
+	write := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		WriteSuccess(w, r, http.StatusOK, "", noMsgpack{})
+		return w
+	}
+
+	w := write(MediaTypeMsgPack + ", " + MediaTypeJSON + ";q=0.5")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MediaTypeJSON || !strings.Contains(w.Body.String(), "json only") {
+		t.Fatalf("JSON fallback: %d %q %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if !strings.Contains(out.String(), "no msgpack form") {
+		t.Fatalf("encode error not logged: %q", out.String())
+	}
+
+	w = write(MediaTypeMsgPack)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("msgpack only: status %d, want 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "json only") {
+		t.Fatal("msgpack-only client received the JSON success body")
+	}
+	var env ErrorEnvelope
+	if err := (msgpackCodec{}).Unmarshal(w.Body.Bytes(), &env); err != nil || env.Error.Code != "ENCODE_ERROR" {
+		t.Fatalf("error envelope = %+v, %v", env, err)
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 1 {
+		t.Fatalf("Vary = %q", vary)
+	}
+}
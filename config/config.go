@@ -28,6 +28,8 @@ type AppConfig struct {
 	Environment string `json:"environment"`
 	Debug       bool   `json:"debug"`
 	Language    string `json:"language"`
+	// Secret signs tokens handed to clients, such as pagination cursors.
+	Secret string `json:"secret,omitempty"`
 }
 
 type ServerConfig struct {
@@ -36,6 +38,8 @@ type ServerConfig struct {
 	ReadTimeoutSeconds  int        `json:"read_timeout_seconds"`
 	WriteTimeoutSeconds int        `json:"write_timeout_seconds"`
 	MaxUploadSizeBytes  int64      `json:"max_upload_size_bytes"`
+	MaxBodySizeBytes    int64      `json:"max_body_size_bytes"`
+	StrictJSON          bool       `json:"strict_json"`
 	Cors                CorsConfig `json:"cors,omitempty"`
 }
 
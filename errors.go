@@ -0,0 +1,159 @@
+package kyugo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	v10 "github.com/go-playground/validator/v10"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+// HTTPError is an error carrying everything needed to render an error
+// envelope. Handlers may return it (or wrap it) and the router's error
+// handler writes it with the declared status.
+type HTTPError struct {
+	Status int
+	// Code and Type follow ErrorExtras semantics.
+	Code string
+	Type string
+	// MessageKey is looked up in the request messages; Message is used
+	// when the key is empty or missing.
+	MessageKey string
+	Message    string
+	Fields     []FieldError
+	Meta       interface{}
+	// Err is the underlying cause. It is logged for 5xx errors and never
+	// sent to clients.
+	Err error
+}
+
+// NewHTTPError returns an HTTPError with the given status, error code/type
+// and localization key.
+func NewHTTPError(status int, code, typ, messageKey string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Type: typ, MessageKey: messageKey}
+}
+
+// Error implements error.
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.MessageKey
+	}
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, msg, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, msg)
+}
+
+// Unwrap returns the wrapped cause.
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+// Wrap returns a copy of e with err as its cause.
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithFields returns a copy of e carrying field errors.
+func (e *HTTPError) WithFields(fields ...FieldError) *HTTPError {
+	c := *e
+	c.Fields = append(append([]FieldError(nil), e.Fields...), fields...)
+	return &c
+}
+
+// WithMeta returns a copy of e carrying meta, rendered as `error.meta`.
+func (e *HTTPError) WithMeta(meta interface{}) *HTTPError {
+	c := *e
+	c.Meta = meta
+	return &c
+}
+
+// Common errors that handlers can return directly or Wrap.
+var (
+	ErrBadRequest   = NewHTTPError(http.StatusBadRequest, "BAD_REQUEST", "INVALID_REQUEST", "locale.bad_request")
+	ErrUnauthorized = NewHTTPError(http.StatusUnauthorized, "UNAUTHORIZED", "AUTHENTICATION_ERROR", "locale.unauthorized")
+	ErrForbidden    = NewHTTPError(http.StatusForbidden, "FORBIDDEN", "AUTHORIZATION_ERROR", "locale.forbidden")
+	ErrNotFound     = NewHTTPError(http.StatusNotFound, "NOT_FOUND", "RESOURCE_NOT_FOUND", "locale.not_found")
+	ErrConflict     = NewHTTPError(http.StatusConflict, "CONFLICT", "RESOURCE_CONFLICT", "locale.conflict")
+	// ErrValidationFailed renders the standard 422 validation envelope;
+	// attach the failures with WithFields.
+	ErrValidationFailed = &HTTPError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR", Type: "INVALID_ATTRIBUTES", MessageKey: "locale.validation_failed", Message: "Validation failed"}
+)
+
+// ErrorHandler renders an error returned by a handler.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler writes err as an error envelope:
+//   - an *HTTPError found with errors.As uses its status, code, type,
+//     localized message, fields and meta;
+//   - validator errors become the standard 422 validation envelope;
+//   - anything else is logged with its cause and reported as a 500.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		writeHTTPError(w, r, he)
+		return
+	}
+	var ve v10.ValidationErrors
+	if errors.As(err, &ve) {
+		writeValidationFailed(w, r, FormatValidationErrors(ve, nil))
+		return
+	}
+	writeHTTPError(w, r, &HTTPError{
+		Status:     http.StatusInternalServerError,
+		Code:       "INTERNAL_ERROR",
+		Type:       "SERVER_ERROR",
+		MessageKey: "locale.internal_error",
+		Err:        err,
+	})
+}
+
+func writeHTTPError(w http.ResponseWriter, r *http.Request, he *HTTPError) {
+	status := he.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if status >= 500 {
+		cause := he.Error()
+		if he.Err != nil {
+			cause = he.Err.Error()
+		}
+		logger.Error("HTTP.Error", logger.Fields{"method": r.Method, "path": r.URL.Path, "status": status, "error": cause})
+	}
+
+	msg := ""
+	if he.MessageKey != "" {
+		msg, _ = Message(r, he.MessageKey)
+	}
+	if msg == "" {
+		msg = he.Message
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+
+	var fields []FieldError
+	if len(he.Fields) > 0 {
+		fields = append(fields, he.Fields...)
+		localizeFieldErrors(r, fields)
+	}
+	WriteError(w, r, status, msg, fields, ErrorExtras{Code: he.Code, Type: he.Type, Meta: he.Meta})
+}
+
+// handleError renders err with the error handler of the router that
+// dispatched r, falling back to DefaultErrorHandler.
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	for rt := RouterFrom(r); rt != nil; rt = rt.parent {
+		if rt.errorHandler != nil {
+			rt.errorHandler(w, r, err)
+			return
+		}
+	}
+	DefaultErrorHandler(w, r, err)
+}
@@ -17,3 +17,13 @@ type CreateProductRequest struct {
 type UpdateProductRequest struct {
 	Name string `json:"name" validate:"required,min=3,max=100"`
 }
+
+type ListProductsQuery struct {
+	Page    int      `query:"page" validate:"omitempty,min=1"`
+	PerPage int      `query:"per_page" validate:"omitempty,min=1,max=100"`
+	Tags    []string `query:"tag"`
+}
+
+type ProductParams struct {
+	ProductID int `path:"productID" validate:"required,min=1"`
+}
@@ -28,11 +28,12 @@ func (ctrl *Controller) Init(s *kyugo.Server) {
 }
 
 func (c *Controller) Index(resp *kyugo.Response, req *kyugo.Request) {
+	query, _ := kyugo.QueryAsRequest[*dto.ListProductsQuery](req)
 	msg, ok := req.Message("locale.product_created")
 	if !ok || msg == "" {
 		msg = "Product created"
 	}
-	resp.JSON(http.StatusOK, msg, map[string]interface{}{"list": []int{1, 2, 3}})
+	resp.JSON(http.StatusOK, msg, map[string]interface{}{"list": []int{1, 2, 3}, "query": query})
 }
 
 func (c *Controller) Create(resp *kyugo.Response, req *kyugo.Request) {
@@ -74,7 +75,8 @@ func (c *Controller) Create(resp *kyugo.Response, req *kyugo.Request) {
 }
 
 func (c *Controller) Show(resp *kyugo.Response, req *kyugo.Request) {
-	id := req.Param("productID")
+	params, _ := kyugo.ParamsAsRequest[*dto.ProductParams](req)
+	id := params.ProductID
 	msg, ok := req.Message("locale.product_created")
 	if !ok || msg == "" {
 		msg = "Product created"
@@ -95,9 +97,9 @@ func (c *Controller) Delete(resp *kyugo.Response, req *kyugo.Request) {
 func (ctrl *Controller) RegisterRoutes(router *kyugo.Router) {
 	group := router.Group("/products")
 
-	group.Get("/", ctrl.Index).ValidateQuery(nil)
+	group.Get("/", ctrl.Index).ValidateQuery(&dto.ListProductsQuery{})
 	group.Post("/", ctrl.Create).ValidateBody(&dto.CreateProductRequest{}).Middleware(middleware.Example)
-	group.Get("/{productID:[0-9]+}", ctrl.Show)
-	group.Patch("/{productID:[0-9]+}", ctrl.Update).ValidateBody(&dto.CreateProductRequest{})
+	group.Get("/{productID:[0-9]+}", ctrl.Show).ValidateParams(&dto.ProductParams{})
+	group.Patch("/{productID:[0-9]+}", ctrl.Update).ValidateParams(&dto.ProductParams{}).ValidateBody(&dto.CreateProductRequest{})
 	group.Delete("/{productID:[0-9]+}", ctrl.Delete)
 }
@@ -1,6 +1,7 @@
 package route
 
 import (
+	"io/fs"
 	"net/http"
 
 	"github.com/go-kyugo/kyugo"
@@ -15,11 +16,10 @@ import (
 
 func Register(server *kyugo.Server, router *kyugo.Router) {
 
-	router.Get("/docs", func(resp *kyugo.Response, req *kyugo.Request) {
-		if err := resp.ServeFile("docs/index.html"); err != nil {
-			resp.JSON(http.StatusNotFound, "File not found", nil)
-		}
-	})
+	// serve resources/docs (loaded in memory by NewServer) under /docs/
+	if docs, err := fs.Sub(kyugo.ResourcesFS(), "docs"); err == nil {
+		router.Static("/docs", docs, kyugo.StaticOptions{CacheControl: "public, max-age=300"})
+	}
 
 	router.Get("/hello/{name}", func(resp *kyugo.Response, req *kyugo.Request) {
 		name := req.Param("name")
@@ -26,6 +26,7 @@ func main() {
 		DefaultMiddlewares: []func(http.Handler) http.Handler{
 			kyugo.CORS(cfg.ConfigVar.Server.Cors),
 			kyugo.LoggerMiddleware,
+			kyugo.Recoverer(cfg.ConfigVar.App.Debug),
 		},
 		ReadTimeout:  time.Duration(cfg.ConfigVar.Server.ReadTimeoutSeconds) * time.Second,
 		WriteTimeout: time.Duration(cfg.ConfigVar.Server.WriteTimeoutSeconds) * time.Second,
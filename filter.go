@@ -0,0 +1,393 @@
+package kyugo
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// FilterOp is a comparison operator of the filter query language.
+type FilterOp string
+
+// Supported filter operators. `filter[name]=v` is shorthand for
+// `filter[name][eq]=v`.
+const (
+	OpEq    FilterOp = "eq"
+	OpNe    FilterOp = "ne"
+	OpGt    FilterOp = "gt"
+	OpGte   FilterOp = "gte"
+	OpLt    FilterOp = "lt"
+	OpLte   FilterOp = "lte"
+	OpLike  FilterOp = "like"  // case-sensitive substring match
+	OpILike FilterOp = "ilike" // case-insensitive substring match
+	OpIn    FilterOp = "in"    // comma-separated list of values
+	OpNull  FilterOp = "null"  // true for IS NULL, false for IS NOT NULL
+)
+
+var filterOps = map[FilterOp]string{
+	OpEq:    "=",
+	OpNe:    "<>",
+	OpGt:    ">",
+	OpGte:   ">=",
+	OpLt:    "<",
+	OpLte:   "<=",
+	OpLike:  "LIKE",
+	OpILike: "ILIKE",
+	OpIn:    "IN",
+	OpNull:  "IS NULL",
+}
+
+// Condition is one `filter[field][op]=value` term. Value is converted to
+// the DTO field's type; it is a []interface{} for OpIn, a bool for OpNull
+// and the raw string for OpLike and OpILike.
+type Condition struct {
+	Field  string
+	Column string
+	Op     FilterOp
+	Value  interface{}
+}
+
+// SortField is one entry of the `sort` parameter.
+type SortField struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Filter is the parsed filter and sort query of a list request. Conditions
+// are combined with AND.
+type Filter struct {
+	Conditions []Condition
+	Sort       []SortField
+}
+
+// ParseFilter parses the `filter[...]` and `sort` parameters of q against
+// dto, a struct (or pointer to one) declaring what clients may use:
+//
+//	type ProductFilter struct {
+//		Name      string    `json:"name" filter:"eq,like,ilike" sort:"true"`
+//		Price     float64   `json:"price" filter:"eq,gt,gte,lt,lte,in" sort:"true"`
+//		Status    string    `json:"status" filter:"eq,ne,in"`
+//		CreatedAt time.Time `json:"created_at" filter:"gte,lte" sort:"true" db:"p.created_at"`
+//	}
+//
+// Fields are named by their json tag. The filter tag lists the operators
+// allowed on a field and sort:"true" makes it sortable; db overrides the
+// column, which defaults to the field name. `sort=-created_at,name` sorts
+// by created_at descending, then name ascending.
+//
+// Unknown fields, disallowed operators, unconvertible values and unsortable
+// fields are reported together as a 422 *HTTPError with one field error
+// each.
+func ParseFilter(q url.Values, dto interface{}) (*Filter, error) {
+	spec := filterSpecOf(dto)
+	f := &Filter{}
+	var fields []FieldError
+
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		if strings.HasPrefix(k, "filter[") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, op, ok := parseFilterKey(key)
+		if !ok {
+			fields = append(fields, FieldError{Field: key, Code: "INVALID_UNKNOWN_FIELD", Message: key + " is not a valid filter"})
+			continue
+		}
+		ff := spec.fields[name]
+		if ff == nil || len(ff.ops) == 0 {
+			fields = append(fields, FieldError{Field: key, Code: "INVALID_UNKNOWN_FIELD", Message: name + " cannot be filtered"})
+			continue
+		}
+		if !ff.allows(op) {
+			allowed := strings.Join(ff.ops, ",")
+			fields = append(fields, FieldError{Field: key, Code: "INVALID_OPERATOR|" + allowed, Message: name + " supports the operators " + allowed})
+			continue
+		}
+		for _, raw := range q[key] {
+			v, err := ff.value(op, raw)
+			if err != nil {
+				fields = append(fields, FieldError{Field: key, Code: "INVALID_TYPE", Message: fmt.Sprintf("invalid value for %s: %v", key, err)})
+				break
+			}
+			f.Conditions = append(f.Conditions, Condition{Field: name, Column: ff.column, Op: op, Value: v})
+		}
+	}
+
+	if raw, ok := q["sort"]; ok {
+		seen := map[string]bool{}
+		for _, s := range strings.Split(strings.Join(raw, ","), ",") {
+			s = strings.TrimSpace(s)
+			desc := strings.HasPrefix(s, "-")
+			name := strings.TrimLeft(s, "-+")
+			if name == "" {
+				continue
+			}
+			ff := spec.fields[name]
+			if ff == nil || !ff.sortable {
+				allowed := strings.Join(spec.sortable, ",")
+				msg := "sort accepts " + allowed
+				if allowed == "" {
+					msg = "sorting is not supported"
+				}
+				fields = append(fields, FieldError{Field: "sort", Code: "INVALID_SORT|" + allowed, Message: msg})
+				break
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			f.Sort = append(f.Sort, SortField{Field: name, Column: ff.column, Desc: desc})
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, ErrValidationFailed.WithFields(fields...)
+	}
+	return f, nil
+}
+
+// Filter parses the request's filter and sort query against dto; see
+// ParseFilter.
+func (r *Request) Filter(dto interface{}) (*Filter, error) {
+	return ParseFilter(r.R.URL.Query(), dto)
+}
+
+// Where returns the conditions as a parameterized Postgres boolean
+// expression, numbering placeholders from $first, with its arguments. It
+// returns "" and no arguments when there are no conditions.
+//
+//	where, args := f.Where(1)
+//	if where != "" {
+//		query += " WHERE " + where
+//	}
+func (f *Filter) Where(first int) (string, []interface{}) {
+	if f == nil {
+		return "", nil
+	}
+	var parts []string
+	var args []interface{}
+	next := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(first+len(args)-1)
+	}
+	for _, c := range f.Conditions {
+		col := quoteColumn(c.Column)
+		switch c.Op {
+		case OpNull:
+			if c.Value == true {
+				parts = append(parts, col+" IS NULL")
+			} else {
+				parts = append(parts, col+" IS NOT NULL")
+			}
+		case OpIn:
+			vals, _ := c.Value.([]interface{})
+			ph := make([]string, len(vals))
+			for i, v := range vals {
+				ph[i] = next(v)
+			}
+			parts = append(parts, col+" IN ("+strings.Join(ph, ", ")+")")
+		case OpLike, OpILike:
+			parts = append(parts, col+" "+filterOps[c.Op]+" "+next("%"+escapeLike(fmt.Sprint(c.Value))+"%"))
+		default:
+			parts = append(parts, col+" "+filterOps[c.Op]+" "+next(c.Value))
+		}
+	}
+	return strings.Join(parts, " AND "), args
+}
+
+// OrderBy returns the sort as an ORDER BY list, or "" when unsorted.
+func (f *Filter) OrderBy() string {
+	if f == nil {
+		return ""
+	}
+	parts := make([]string, len(f.Sort))
+	for i, s := range f.Sort {
+		dir := " ASC"
+		if s.Desc {
+			dir = " DESC"
+		}
+		parts[i] = quoteColumn(s.Column) + dir
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SQL returns the `WHERE ... ORDER BY ...` clauses (either may be absent)
+// and their arguments, numbering placeholders from $first:
+//
+//	clause, args := f.SQL(1)
+//	rows, err := db.SQL.QueryContext(ctx, "SELECT id, name, price FROM products "+clause, args...)
+func (f *Filter) SQL(first int) (string, []interface{}) {
+	where, args := f.Where(first)
+	var b strings.Builder
+	if where != "" {
+		b.WriteString("WHERE " + where)
+	}
+	if order := f.OrderBy(); order != "" {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString("ORDER BY " + order)
+	}
+	return b.String(), args
+}
+
+// parseFilterKey splits `filter[name]` and `filter[name][op]`.
+func parseFilterKey(key string) (string, FilterOp, bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+	i := strings.IndexByte(rest, ']')
+	if i <= 0 {
+		return "", "", false
+	}
+	name, rest := rest[:i], rest[i+1:]
+	if rest == "" {
+		return name, OpEq, true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+	return name, FilterOp(rest[1 : len(rest)-1]), true
+}
+
+// quoteColumn quotes each dot-separated part of a column reference.
+func quoteColumn(col string) string {
+	parts := strings.Split(col, ".")
+	for i, p := range parts {
+		parts[i] = pq.QuoteIdentifier(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string { return likeEscaper.Replace(s) }
+
+type filterSpec struct {
+	fields   map[string]*filterField
+	sortable []string
+}
+
+type filterField struct {
+	column   string
+	typ      reflect.Type
+	ops      []string
+	sortable bool
+}
+
+var filterSpecs sync.Map // reflect.Type -> *filterSpec
+
+func (ff *filterField) allows(op FilterOp) bool {
+	for _, o := range ff.ops {
+		if FilterOp(o) == op {
+			return true
+		}
+	}
+	return false
+}
+
+// value converts raw for op.
+func (ff *filterField) value(op FilterOp, raw string) (interface{}, error) {
+	switch op {
+	case OpNull:
+		return strconv.ParseBool(raw)
+	case OpLike, OpILike:
+		return raw, nil
+	case OpIn:
+		var out []interface{}
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			v, err := ff.convert(s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("empty list")
+		}
+		return out, nil
+	}
+	return ff.convert(raw)
+}
+
+func (ff *filterField) convert(s string) (interface{}, error) {
+	v := reflect.New(ff.typ).Elem()
+	if err := setScalar(v, s); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// filterSpecOf returns the cached filter declaration of dto's type. It
+// panics on unknown operators in filter tags.
+func filterSpecOf(dto interface{}) *filterSpec {
+	t := reflect.TypeOf(dto)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("kyugo: filter dto must be a struct, got %v", reflect.TypeOf(dto)))
+	}
+	if s, ok := filterSpecs.Load(t); ok {
+		return s.(*filterSpec)
+	}
+	spec := &filterSpec{fields: map[string]*filterField{}}
+	collectFilterFields(t, spec)
+	sort.Strings(spec.sortable)
+	s, _ := filterSpecs.LoadOrStore(t, spec)
+	return s.(*filterSpec)
+}
+
+func collectFilterFields(t reflect.Type, spec *filterSpec) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		// embedded structs contribute their fields to the parent
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			collectFilterFields(sf.Type, spec)
+			continue
+		}
+		name, ok := fieldKey(sf)
+		if !ok {
+			continue
+		}
+		ff := &filterField{column: name, typ: sf.Type}
+		for ff.typ.Kind() == reflect.Ptr || (ff.typ.Kind() == reflect.Slice && ff.typ.Elem().Kind() != reflect.Uint8) {
+			ff.typ = ff.typ.Elem()
+		}
+		if col, ok := tagName(sf, "db"); ok && col != "-" {
+			ff.column = col
+		}
+		for _, op := range strings.Split(sf.Tag.Get("filter"), ",") {
+			if op = strings.TrimSpace(op); op == "" {
+				continue
+			}
+			if _, ok := filterOps[FilterOp(op)]; !ok {
+				panic(fmt.Sprintf("kyugo: %s.%s: unknown filter operator %q", t.Name(), sf.Name, op))
+			}
+			ff.ops = append(ff.ops, op)
+		}
+		ff.sortable, _ = strconv.ParseBool(sf.Tag.Get("sort"))
+		if len(ff.ops) == 0 && !ff.sortable {
+			continue
+		}
+		if ff.sortable {
+			spec.sortable = append(spec.sortable, name)
+		}
+		spec.fields[name] = ff
+	}
+}
@@ -0,0 +1,144 @@
+package kyugo
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type productFilter struct {
+	Name      string    `json:"name" filter:"eq,like,ilike" sort:"true"`
+	Price     float64   `json:"price" filter:"eq,gt,gte,lt,lte,in" sort:"true"`
+	Status    *string   `json:"status" filter:"eq,ne,in,null"`
+	CreatedAt time.Time `json:"created_at" filter:"gte,lte" sort:"true" db:"p.created_at"`
+	Secret    string    `json:"secret"`
+}
+
+func mustParseFilter(t *testing.T, query string) *Filter {
+	t.Helper()
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseFilter(q, productFilter{})
+	if err != nil {
+		t.Fatalf("ParseFilter(%q): %v", query, err)
+	}
+	return f
+}
+
+func TestFilterWherePlaceholders(t *testing.T) {
+	f := mustParseFilter(t, "filter[price][gte]=10&filter[price][in]=1,2,3&filter[status]=draft&filter[created_at][lte]=2024-01-02T00:00:00Z")
+	where, args := f.Where(3)
+
+	want := `"p"."created_at" <= $3 AND "price" >= $4 AND "price" IN ($5, $6, $7) AND "status" = $8`
+	if where != want {
+		t.Fatalf("Where =\n  %s\nwant\n  %s", where, want)
+	}
+	wantArgs := []interface{}{
+		time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		float64(10), float64(1), float64(2), float64(3),
+		"draft",
+	}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("args = %#v", args)
+	}
+	for i := range wantArgs {
+		if tm, ok := wantArgs[i].(time.Time); ok {
+			if got, _ := args[i].(time.Time); !got.Equal(tm) {
+				t.Errorf("args[%d] = %#v, want %v", i, args[i], tm)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(args[i], wantArgs[i]) {
+			t.Errorf("args[%d] = %#v, want %#v", i, args[i], wantArgs[i])
+		}
+	}
+}
+
+func TestFilterLikeEscapesWildcards(t *testing.T) {
+	f := mustParseFilter(t, "filter[name][ilike]="+url.QueryEscape(`50%_x\`))
+	where, args := f.Where(1)
+	if where != `"name" ILIKE $1` {
+		t.Fatalf("Where = %s", where)
+	}
+	if len(args) != 1 || args[0] != `%50\%\_x\\%` {
+		t.Fatalf("args = %#v", args)
+	}
+}
+
+func TestFilterNull(t *testing.T) {
+	where, args := mustParseFilter(t, "filter[status][null]=true").Where(1)
+	if where != `"status" IS NULL` || len(args) != 0 {
+		t.Fatalf("null=true: %s %v", where, args)
+	}
+	where, _ = mustParseFilter(t, "filter[status][null]=false").Where(1)
+	if where != `"status" IS NOT NULL` {
+		t.Fatalf("null=false: %s", where)
+	}
+}
+
+func TestFilterOrderByAndSQL(t *testing.T) {
+	f := mustParseFilter(t, "filter[name]=a&sort=-created_at,name,-created_at")
+	if got := f.OrderBy(); got != `"p"."created_at" DESC, "name" ASC` {
+		t.Fatalf("OrderBy = %s", got)
+	}
+	clause, args := f.SQL(1)
+	if clause != `WHERE "name" = $1 ORDER BY "p"."created_at" DESC, "name" ASC` || len(args) != 1 {
+		t.Fatalf("SQL = %s %v", clause, args)
+	}
+
+	var empty *Filter
+	if clause, args := empty.SQL(1); clause != "" || args != nil {
+		t.Fatalf("nil filter SQL = %q %v", clause, args)
+	}
+	if clause, _ := mustParseFilter(t, "").SQL(1); clause != "" {
+		t.Fatalf("empty filter SQL = %q", clause)
+	}
+}
+
+func TestFilterQuotesColumns(t *testing.T) {
+	if got := quoteColumn(`weird"col`); got != `"weird""col"` {
+		t.Fatalf("quoteColumn = %s", got)
+	}
+	if got := quoteColumn("p.created_at"); got != `"p"."created_at"` {
+		t.Fatalf("quoteColumn = %s", got)
+	}
+}
+
+func TestFilterReportsEveryError(t *testing.T) {
+	q, _ := url.ParseQuery("filter[secret]=x&filter[name][gt]=a&filter[price]=cheap&filter[oops=1&sort=status")
+	_, err := ParseFilter(q, &productFilter{})
+	var he *HTTPError
+	if !errors.As(err, &he) || he.Status != 422 {
+		t.Fatalf("err = %v, want a 422", err)
+	}
+	codes := map[string]string{}
+	for _, fe := range he.Fields {
+		codes[fe.Field] = fe.Code
+	}
+	want := map[string]string{
+		"filter[secret]":   "INVALID_UNKNOWN_FIELD",
+		"filter[name][gt]": "INVALID_OPERATOR|eq,like,ilike",
+		"filter[price]":    "INVALID_TYPE",
+		"filter[oops":      "INVALID_UNKNOWN_FIELD",
+		"sort":             "INVALID_SORT|created_at,name,price",
+	}
+	if !reflect.DeepEqual(codes, want) {
+		t.Fatalf("field errors = %v\nwant %v", codes, want)
+	}
+}
+
+func TestFilterRejectsUnknownOperatorTags(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "unknown filter operator") {
+			t.Fatalf("recover() = %v", r)
+		}
+	}()
+	ParseFilter(url.Values{}, struct {
+		Name string `json:"name" filter:"regex"`
+	}{})
+}
@@ -0,0 +1,355 @@
+package kyugo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	v10 "github.com/go-playground/validator/v10"
+)
+
+const validatedFormKey ctxKey = "youu.validated_form"
+
+// multipartMemory is the part of a multipart body kept in memory; larger
+// files are spooled to temporary files removed after the handler returns.
+const multipartMemory = 32 << 20
+
+var uploadedFileType = reflect.TypeOf(UploadedFile{})
+
+// UploadedFile is a file received in a multipart form. DTO fields of type
+// UploadedFile, *UploadedFile, []UploadedFile or []*UploadedFile are bound
+// by ValidateForm from the part named by their `form:"..."` tag.
+type UploadedFile struct {
+	// Filename is the name supplied by the client.
+	Filename string
+	Size     int64
+	// ContentType is detected from the file contents, not taken from the
+	// client's part header.
+	ContentType string
+	Header      *multipart.FileHeader
+
+	mime *mimetype.MIME
+}
+
+// Open opens the uploaded file for reading.
+func (f *UploadedFile) Open() (multipart.File, error) {
+	if f == nil || f.Header == nil {
+		return nil, errors.New("no uploaded file")
+	}
+	return f.Header.Open()
+}
+
+// newUploadedFile wraps fh, sniffing its MIME type.
+func newUploadedFile(fh *multipart.FileHeader) (*UploadedFile, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := mimetype.DetectReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadedFile{Filename: fh.Filename, Size: fh.Size, ContentType: m.String(), Header: fh, mime: m}, nil
+}
+
+// ValidateForm registers a DTO value used to bind and validate an
+// `application/x-www-form-urlencoded` or `multipart/form-data` body for the
+// previously registered route. Values are matched with the `form:"..."`
+// tag (falling back to the json tag) and uploaded files bind to
+// UploadedFile fields. Besides the usual rules, file fields accept
+// `filesize=2MB` and `mimetype=image/png image/jpeg` (wildcards such as
+// `image/*` are allowed). The bound value is available via FormAs.
+func (rc *RouteChain) ValidateForm(dto interface{}) *RouteChain {
+	return rc.update(func(r *route) { r.form = typeOf(dto) })
+}
+
+// MaxBodySize limits the request body of the previously registered route
+// to n bytes. Larger bodies are rejected with a 413 error envelope. It
+// overrides the router's upload limit for form routes.
+func (rc *RouteChain) MaxBodySize(n int64) *RouteChain {
+	return rc.update(func(r *route) { r.maxBodySize = n })
+}
+
+// MaxUploadSize sets the body limit, in bytes, for routes using
+// ValidateForm that declare no MaxBodySize. NewServer sets it from
+// `server.max_upload_size_bytes`. Mounted routers inherit the limit.
+func (rt *Router) MaxUploadSize(n int64) *Router {
+	rt.maxUploadSize = n
+	return rt
+}
+
+// uploadLimit returns the upload limit of rt or its closest ancestor.
+func (rt *Router) uploadLimit() int64 {
+	for ; rt != nil; rt = rt.parent {
+		if rt.maxUploadSize > 0 {
+			return rt.maxUploadSize
+		}
+	}
+	return 0
+}
+
+// FormAs retrieves a previously-validated form DTO (set by ValidateForm)
+// from the request context and asserts it to T.
+func FormAs[T any](r *http.Request) (T, bool) {
+	return contextValueAs[T](r, validatedFormKey)
+}
+
+// FormAsRequest is the Request counterpart of FormAs.
+func FormAsRequest[T any](r *Request) (T, bool) {
+	var zero T
+	if r == nil || r.R == nil {
+		return zero, false
+	}
+	return FormAs[T](r.R)
+}
+
+// limitBody caps the body of r at n bytes. Requests announcing a larger
+// Content-Length are rejected straight away; others fail while reading.
+func limitBody(w http.ResponseWriter, r *http.Request, n int64) bool {
+	if n <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+	if r.ContentLength > n {
+		writePayloadTooLarge(w, r, n)
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, n)
+	return true
+}
+
+// validateFormStep parses a form body and binds it into the DTO type t
+// registered with ValidateForm, then validates it.
+func validateFormStep(w http.ResponseWriter, r *http.Request, t reflect.Type) (*http.Request, bool) {
+	if t == nil {
+		return r, true
+	}
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var err error
+	switch mt {
+	case "multipart/form-data":
+		err = r.ParseMultipartForm(multipartMemory)
+	case "application/x-www-form-urlencoded":
+		err = r.ParseForm()
+	default:
+		writeUnsupportedMediaType(w, r, []string{"multipart/form-data", "application/x-www-form-urlencoded"})
+		return r, false
+	}
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			writePayloadTooLarge(w, r, mbe.Limit)
+		} else {
+			writeInvalidBody(w, r)
+		}
+		return r, false
+	}
+
+	v := newDTO(t)
+	if fields := bindValues(r.PostForm, "form", v); len(fields) > 0 {
+		writeValidationFailed(w, r, fields)
+		return r, false
+	}
+	if r.MultipartForm != nil {
+		if err := bindFiles(r.MultipartForm.File, v); err != nil {
+			writeInvalidBody(w, r)
+			return r, false
+		}
+	}
+	if err := Validate(v); err != nil {
+		writeValidationFailed(w, r, formatValidationErrors(err, v, "form"))
+		return r, false
+	}
+	ctx := context.WithValue(r.Context(), validatedFormKey, v)
+	return r.WithContext(ctx), true
+}
+
+// bindFiles assigns uploaded files to the UploadedFile fields of the struct
+// pointed to by dst.
+func bindFiles(files map[string][]*multipart.FileHeader, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return bindFileStruct(files, rv.Elem())
+}
+
+func bindFileStruct(files map[string][]*multipart.FileHeader, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := bindFileStruct(files, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		key, ok := fieldKey(sf, "form")
+		if !ok || len(files[key]) == 0 {
+			continue
+		}
+		headers := files[key]
+
+		ft := sf.Type
+		slice := ft.Kind() == reflect.Slice
+		if slice {
+			ft = ft.Elem()
+		}
+		ptr := ft.Kind() == reflect.Ptr
+		if ptr {
+			ft = ft.Elem()
+		}
+		if ft != uploadedFileType {
+			continue
+		}
+		if !slice {
+			headers = headers[:1]
+		}
+
+		out := reflect.MakeSlice(reflect.SliceOf(sf.Type), 0, len(headers))
+		if slice {
+			out = reflect.MakeSlice(sf.Type, 0, len(headers))
+		}
+		for _, fh := range headers {
+			uf, err := newUploadedFile(fh)
+			if err != nil {
+				return fmt.Errorf("file %s: %w", key, err)
+			}
+			v := reflect.ValueOf(uf)
+			if !ptr {
+				v = v.Elem()
+			}
+			out = reflect.Append(out, v)
+		}
+		if slice {
+			fv.Set(out)
+		} else {
+			fv.Set(out.Index(0))
+		}
+	}
+	return nil
+}
+
+// writePayloadTooLarge writes the standard 413 envelope for bodies over
+// limit bytes.
+func writePayloadTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	msg, ok := Message(r, "locale.payload_too_large")
+	if !ok || msg == "" {
+		msg = "Request body too large"
+	}
+	WriteError(w, r, http.StatusRequestEntityTooLarge, msg, nil, ErrorExtras{
+		Code: "PAYLOAD_TOO_LARGE",
+		Type: "BODY_TOO_LARGE",
+		Meta: map[string]interface{}{"limit": limit},
+	})
+}
+
+// registerFileRules adds the `filesize` and `mimetype` rules for
+// UploadedFile fields to v.
+func registerFileRules(v *v10.Validate) {
+	_ = v.RegisterValidation("filesize", func(fl v10.FieldLevel) bool {
+		files, ok := fieldFiles(fl)
+		if !ok {
+			return false
+		}
+		max, err := parseByteSize(fl.Param())
+		if err != nil {
+			return false
+		}
+		for _, f := range files {
+			if f.Size > max {
+				return false
+			}
+		}
+		return true
+	})
+	_ = v.RegisterValidation("mimetype", func(fl v10.FieldLevel) bool {
+		files, ok := fieldFiles(fl)
+		if !ok {
+			return false
+		}
+		wants := strings.Fields(fl.Param())
+		for _, f := range files {
+			if !f.hasMIME(wants) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// hasMIME reports whether f matches one of wants, which may hold
+// wildcards such as "image/*".
+func (f *UploadedFile) hasMIME(wants []string) bool {
+	for _, want := range wants {
+		if strings.HasSuffix(want, "/*") {
+			if strings.HasPrefix(f.ContentType, strings.TrimSuffix(want, "*")) {
+				return true
+			}
+			continue
+		}
+		if f.mime != nil && f.mime.Is(want) || f.ContentType == want {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldFiles returns the files held by an UploadedFile, *UploadedFile,
+// []UploadedFile or []*UploadedFile field. File rules on a slice apply to
+// every file in it.
+func fieldFiles(fl v10.FieldLevel) ([]*UploadedFile, bool) {
+	switch f := fl.Field().Interface().(type) {
+	case UploadedFile:
+		return []*UploadedFile{&f}, true
+	case *UploadedFile:
+		return []*UploadedFile{f}, f != nil
+	case []*UploadedFile:
+		for _, uf := range f {
+			if uf == nil {
+				return nil, false
+			}
+		}
+		return f, true
+	case []UploadedFile:
+		out := make([]*UploadedFile, len(f))
+		for i := range f {
+			out[i] = &f[i]
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// parseByteSize parses sizes such as "512", "100KB", "2MB" or "1GB"
+// (binary multiples).
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		n      int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.n
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,150 @@
+package kyugo
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+type formPart struct {
+	field, filename string
+	content         []byte
+}
+
+// multipartBody encodes parts as a multipart/form-data body.
+func multipartBody(t *testing.T, parts ...formPart) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		var w io.Writer
+		var err error
+		if p.filename == "" {
+			w, err = mw.CreateFormField(p.field)
+		} else {
+			w, err = mw.CreateFormFile(p.field, p.filename)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(p.content)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf, mw.FormDataContentType()
+}
+
+func postForm(rt *Router, target string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, target, body)
+	r.Header.Set("Content-Type", contentType)
+	rt.ServeHTTP(w, r)
+	return w
+}
+
+type avatarForm struct {
+	Name   string        `form:"name" validate:"required"`
+	Avatar *UploadedFile `form:"avatar" validate:"required,filesize=1KB,mimetype=image/*"`
+}
+
+type galleryForm struct {
+	Photos []*UploadedFile `form:"photos" validate:"required,filesize=1KB,mimetype=image/png"`
+	Covers []UploadedFile  `form:"covers" validate:"omitempty,mimetype=image/png"`
+}
+
+func TestValidateFormBindsFilePointersAndSlices(t *testing.T) {
+	rt := NewRouter()
+	rt.Post("/avatar", func(resp *Response, req *Request) {
+		f, _ := FormAsRequest[*avatarForm](req)
+		resp.JSON(http.StatusOK, "", map[string]interface{}{"name": f.Name, "file": f.Avatar.Filename, "type": f.Avatar.ContentType})
+	}).ValidateForm(&avatarForm{})
+	rt.Post("/gallery", func(resp *Response, req *Request) {
+		f, _ := FormAsRequest[*galleryForm](req)
+		resp.JSON(http.StatusOK, "", map[string]int{"photos": len(f.Photos), "covers": len(f.Covers)})
+	}).ValidateForm(&galleryForm{})
+
+	body, ct := multipartBody(t, formPart{field: "name", content: []byte("ada")}, formPart{"avatar", "a.png", pngHeader})
+	w := postForm(rt, "/avatar", body, ct)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"file":"a.png"`) || !strings.Contains(w.Body.String(), `"type":"image/png"`) {
+		t.Fatalf("pointer field: %d %s", w.Code, w.Body.String())
+	}
+
+	body, ct = multipartBody(t, formPart{"photos", "1.png", pngHeader}, formPart{"photos", "2.png", pngHeader}, formPart{"covers", "c.png", pngHeader})
+	w = postForm(rt, "/gallery", body, ct)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `{"covers":1,"photos":2}`) {
+		t.Fatalf("slice fields: %d %s", w.Code, w.Body.String())
+	}
+
+	// every file of a slice is checked
+	body, ct = multipartBody(t, formPart{"photos", "1.png", pngHeader}, formPart{"photos", "2.txt", []byte("plain text")})
+	w = postForm(rt, "/gallery", body, ct)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"field":"photos"`) {
+		t.Fatalf("slice with a text file: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestValidateFormFileRules(t *testing.T) {
+	rt := NewRouter()
+	rt.Post("/avatar", func(w http.ResponseWriter, r *http.Request) {}).ValidateForm(&avatarForm{})
+
+	for name, tc := range map[string]struct {
+		file []byte
+		code string
+	}{
+		"wrong MIME type": {[]byte("just some text"), "mimetype"},
+		"too large":       {append(append([]byte{}, pngHeader...), make([]byte, 2<<10)...), "filesize"},
+	} {
+		body, ct := multipartBody(t, formPart{field: "name", content: []byte("ada")}, formPart{"avatar", "a.png", tc.file})
+		w := postForm(rt, "/avatar", body, ct)
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"field":"avatar"`) || !strings.Contains(w.Body.String(), tc.code) {
+			t.Errorf("%s: %d %s", name, w.Code, w.Body.String())
+		}
+	}
+
+	body, ct := multipartBody(t, formPart{field: "name", content: []byte("ada")})
+	if w := postForm(rt, "/avatar", body, ct); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"field":"avatar"`) {
+		t.Fatalf("missing file: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMaxUploadSize(t *testing.T) {
+	rt := NewRouter().MaxUploadSize(1 << 10)
+	rt.Post("/avatar", func(w http.ResponseWriter, r *http.Request) {}).ValidateForm(&avatarForm{})
+	rt.Post("/raw", readAll)
+	big := append(append([]byte{}, pngHeader...), make([]byte, 4<<10)...)
+
+	// an announced Content-Length over the limit is refused before reading
+	body, ct := multipartBody(t, formPart{field: "name", content: []byte("ada")}, formPart{"avatar", "a.png", big})
+	w := postForm(rt, "/avatar", body, ct)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), `"limit":1024`) {
+		t.Fatalf("oversize Content-Length: %d %s", w.Code, w.Body.String())
+	}
+
+	// a body without Content-Length fails once the limit is read
+	body, ct = multipartBody(t, formPart{field: "name", content: []byte("ada")}, formPart{"avatar", "a.png", big})
+	w = postForm(rt, "/avatar", io.MultiReader(body), ct)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("streamed body: %d %s", w.Code, w.Body.String())
+	}
+
+	// the upload limit applies to form routes only
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/raw", bytes.NewReader(make([]byte, 4<<10))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("non-form route: %d", w.Code)
+	}
+
+	// a route's MaxBodySize overrides the router's upload limit
+	rt.Post("/large", func(w http.ResponseWriter, r *http.Request) {}).ValidateForm(&avatarForm{}).MaxBodySize(8 << 10)
+	body, ct = multipartBody(t, formPart{field: "name", content: []byte("ada")}, formPart{"avatar", "a.png", pngHeader})
+	if w := postForm(rt, "/large", body, ct); w.Code != http.StatusOK {
+		t.Fatalf("route MaxBodySize: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,160 @@
+package kyugo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// hostRoutes is the route tree of one host pattern.
+type hostRoutes struct {
+	pattern string
+	re      *regexp.Regexp
+	names   []string
+	mux     chi.Router
+}
+
+// Host returns a group whose routes only match requests for hosts matching
+// pattern. Placeholders capture one label each ({tenant}.example.com) or a
+// custom regex ({region:[a-z]{2}}.api.example.com); their values are
+// readable with Request.Param and bound by ValidateParams like path
+// params. Matching ignores case and the port. Routes without a host keep
+// serving every host, and host routes are tried first.
+//
+//	tenant := r.Host("{tenant}.example.com")
+//	tenant.Get("/dashboard", ctrl.Dashboard).Name("tenant.dashboard")
+//	r.URLFor("tenant.dashboard", map[string]string{"tenant": "acme"})
+//	// https://acme.example.com/dashboard
+func (rt *Router) Host(pattern string) *Group {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	for _, h := range rt.hosts {
+		if h.pattern == pattern {
+			return &Group{parent: h.mux, router: rt, host: pattern}
+		}
+	}
+
+	h := &hostRoutes{pattern: pattern, mux: chi.NewRouter()}
+	expr := "(?i)^"
+	last := 0
+	for _, loc := range routeParamRe.FindAllStringSubmatchIndex(pattern, -1) {
+		expr += regexp.QuoteMeta(pattern[last:loc[0]])
+		h.names = append(h.names, pattern[loc[2]:loc[3]])
+		part := `[^.]+`
+		if loc[4] >= 0 {
+			part = pattern[loc[4]+1 : loc[5]]
+		}
+		expr += "(" + part + ")"
+		last = loc[1]
+	}
+	expr += regexp.QuoteMeta(pattern[last:]) + "$"
+	h.re = regexp.MustCompile(expr)
+
+	h.mux.NotFound(rt.notFound)
+	h.mux.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		rt.methodNotAllowedIn(h.mux, w, r)
+	})
+	rt.hosts = append(rt.hosts, h)
+	return &Group{parent: h.mux, router: rt, host: pattern}
+}
+
+// URLScheme sets the scheme URLFor uses for host-bound routes. Defaults to
+// "https".
+func (rt *Router) URLScheme(scheme string) *Router {
+	rt.urlScheme = scheme
+	return rt
+}
+
+func (rt *Router) scheme() string {
+	for p := rt; p != nil; p = p.parent {
+		if p.urlScheme != "" {
+			return p.urlScheme
+		}
+	}
+	return "https"
+}
+
+// serveHost dispatches r to the first host tree whose pattern matches the
+// request host and which has a route for its method and path. Requests
+// whose method only a host tree lacks fall through to the host-independent
+// routes; when those do not have the path either, the host tree answers
+// with its 405. It reports whether r was handled.
+func (rt *Router) serveHost(w http.ResponseWriter, r *http.Request) bool {
+	host := requestHost(r)
+	p := routingPath(r)
+	var fallback *hostRoutes
+	var fallbackMatch []string
+	for _, h := range rt.hosts {
+		m := h.re.FindStringSubmatch(host)
+		if m == nil {
+			continue
+		}
+		if h.mux.Match(chi.NewRouteContext(), r.Method, p) {
+			h.serve(w, r, m)
+			return true
+		}
+		if fallback == nil && matchesAnyMethodIn(h.mux, p) {
+			fallback, fallbackMatch = h, m
+		}
+	}
+	if fallback == nil || matchesAnyMethodIn(rt.r, p) {
+		return false
+	}
+	fallback.serve(w, r, fallbackMatch)
+	return true
+}
+
+// serve runs r through the host tree with the host params of m.
+func (h *hostRoutes) serve(w http.ResponseWriter, r *http.Request, m []string) {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		rctx = chi.NewRouteContext()
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	}
+	for i, name := range h.names {
+		rctx.URLParams.Add(name, m[i+1])
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// hostServes reports whether a host tree matching the request host has a
+// route for path.
+func (rt *Router) hostServes(r *http.Request, path string) bool {
+	host := requestHost(r)
+	for _, h := range rt.hosts {
+		if h.re.MatchString(host) && matchesAnyMethodIn(h.mux, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestHost returns the host of r without its port.
+func requestHost(r *http.Request) string {
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		return h
+	}
+	return r.Host
+}
+
+// routingPath returns the path chi routes r by: the remaining route path
+// inside mounted routers, the URL path otherwise.
+func routingPath(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		return rctx.RoutePath
+	}
+	return r.URL.Path
+}
+
+// matchesAnyMethodIn reports whether some route of mux serves path.
+func matchesAnyMethodIn(mux chi.Router, path string) bool {
+	for _, m := range AnyMethods {
+		if mux.Match(chi.NewRouteContext(), m, path) {
+			return true
+		}
+	}
+	return false
+}
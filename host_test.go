@@ -0,0 +1,91 @@
+package kyugo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func hostGet(rt *Router, host, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Host = host
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	rt.ServeHTTP(w, r)
+	return w
+}
+
+func TestHostRoutes(t *testing.T) {
+	rt := NewRouter()
+	tenant := rt.Host("{tenant}.example.com")
+	tenant.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tenant " + (&Request{R: r}).Param("tenant")))
+	})
+	rt.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("global"))
+	})
+
+	if got := hostGet(rt, "Acme.Example.com:8080", "/whoami", nil).Body.String(); got != "tenant Acme" {
+		t.Fatalf("tenant host = %q", got)
+	}
+	if got := hostGet(rt, "other.org", "/whoami", nil).Body.String(); got != "global" {
+		t.Fatalf("other host = %q", got)
+	}
+}
+
+func TestHostRoutesAreVersioned(t *testing.T) {
+	rt := NewRouter().Versioning(VersioningOptions{})
+	tenant := rt.Host("{tenant}.example.com")
+	tenant.Get("/items", func(resp *Response, req *Request) {
+		_, _ = resp.W.Write([]byte("v1"))
+	})
+	tenant.Version("2").Get("/items", func(resp *Response, req *Request) {
+		_, _ = resp.W.Write([]byte("v" + req.Version()))
+	})
+
+	if got := hostGet(rt, "acme.example.com", "/items", nil).Body.String(); got != "v1" {
+		t.Fatalf("unversioned request = %q", got)
+	}
+	if got := hostGet(rt, "acme.example.com", "/items", http.Header{"Api-Version": {"2"}}).Body.String(); got != "v2" {
+		t.Fatalf("API-Version: 2 = %q", got)
+	}
+	accept := http.Header{"Accept": {"application/vnd.app.v2+json"}}
+	if got := hostGet(rt, "acme.example.com", "/items", accept).Body.String(); got != "v2" {
+		t.Fatalf("vendor Accept = %q", got)
+	}
+	if got := hostGet(rt, "acme.example.com", "/v2/items", nil).Body.String(); got != "v2" {
+		t.Fatalf("prefixed path = %q", got)
+	}
+}
+
+func TestHostRoutesFallThroughOnMethod(t *testing.T) {
+	rt := NewRouter()
+	api := rt.Host("api.example.com")
+	api.Post("/items", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("host post"))
+	})
+	api.Post("/orders", func(w http.ResponseWriter, r *http.Request) {})
+	rt.Get("/items", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("global get"))
+	})
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, nil)
+		r.Host = "api.example.com"
+		rt.ServeHTTP(w, r)
+		return w
+	}
+	if w := serve(http.MethodGet, "/items"); w.Code != http.StatusOK || w.Body.String() != "global get" {
+		t.Fatalf("GET /items: %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(http.MethodPost, "/items"); w.Body.String() != "host post" {
+		t.Fatalf("POST /items: %d %q", w.Code, w.Body.String())
+	}
+	// only the host tree has the path, so it reports the wrong method
+	if w := serve(http.MethodGet, "/orders"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /orders: %d, want 405", w.Code)
+	}
+}
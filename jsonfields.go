@@ -0,0 +1,147 @@
+package kyugo
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// jsonField is a struct field as encoding/json sees it.
+type jsonField struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	tagged    bool
+}
+
+// jsonFields are the fields encoding/json encodes and decodes for a struct
+// type. Strict decoding and sparse fieldsets both use them so a request
+// body and a response agree on which names exist.
+type jsonFields struct {
+	list   []jsonField
+	byName map[string]jsonField
+}
+
+var jsonFieldCache sync.Map // reflect.Type -> *jsonFields
+
+// jsonFieldsOf lists the fields of the struct type t in encoding order,
+// following the rules of encoding/json: fields of untagged embedded
+// structs are promoted, the shallowest field wins a name, a tagged field
+// wins among equally shallow ones and names that stay ambiguous are
+// dropped.
+func jsonFieldsOf(t reflect.Type) *jsonFields {
+	if f, ok := jsonFieldCache.Load(t); ok {
+		return f.(*jsonFields)
+	}
+	type candidate struct {
+		jsonField
+		depth int
+	}
+	type level struct {
+		typ   reflect.Type
+		index []int
+	}
+	var all []candidate
+	visited := map[reflect.Type]bool{}
+	next := []level{{typ: t}}
+	for depth := 0; len(next) > 0; depth++ {
+		current := next
+		next = nil
+		for _, l := range current {
+			// a type embedded twice at one depth is walked twice so its
+			// fields cancel out, as in encoding/json
+			if visited[l.typ] {
+				continue
+			}
+			for i := 0; i < l.typ.NumField(); i++ {
+				sf := l.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				idx := append(append([]int(nil), l.index...), i)
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, level{typ: ft, index: idx})
+					continue
+				}
+				tagged := name != ""
+				if !tagged {
+					name = sf.Name
+				}
+				all = append(all, candidate{jsonField{
+					name:      name,
+					index:     idx,
+					typ:       sf.Type,
+					omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+					tagged:    tagged,
+				}, depth})
+			}
+		}
+		for _, l := range current {
+			visited[l.typ] = true
+		}
+	}
+
+	// order each name's candidates by depth, tagged first, then pick the
+	// dominant one like encoding/json
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		if all[i].depth != all[j].depth {
+			return all[i].depth < all[j].depth
+		}
+		return all[i].tagged && !all[j].tagged
+	})
+	fields := &jsonFields{byName: map[string]jsonField{}}
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		if j-i == 1 || all[i].depth < all[i+1].depth || all[i].tagged != all[i+1].tagged {
+			fields.list = append(fields.list, all[i].jsonField)
+			fields.byName[all[i].name] = all[i].jsonField
+		}
+		i = j
+	}
+	sort.Slice(fields.list, func(i, j int) bool {
+		a, b := fields.list[i].index, fields.list[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	f, _ := jsonFieldCache.LoadOrStore(t, fields)
+	return f.(*jsonFields)
+}
+
+// lookup matches key like encoding/json: exactly, then without regard to
+// case.
+func (f *jsonFields) lookup(key string) (jsonField, bool) {
+	if field, ok := f.byName[key]; ok {
+		return field, true
+	}
+	for _, field := range f.list {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return jsonField{}, false
+}
@@ -0,0 +1,94 @@
+package kyugo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type jsonAudit struct {
+	ID      int    `json:"id"`
+	Created string `json:"created"`
+	Note    string
+}
+
+type jsonOwner struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type jsonTagged struct {
+	Note string `json:"Note"`
+}
+
+type jsonRecord struct {
+	jsonAudit
+	*jsonOwner
+	jsonTagged
+	Title  string `json:"title"`
+	Hidden string `json:"-"`
+	Dash   string `json:"-,"`
+	secret string
+}
+
+func TestJSONFieldsFollowEncodingJSON(t *testing.T) {
+	b, err := json.Marshal(jsonRecord{jsonOwner: &jsonOwner{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	var want []string
+	_, _ = dec.Token()
+	for dec.More() {
+		tok, _ := dec.Token()
+		want = append(want, tok.(string))
+		var skip json.RawMessage
+		_ = dec.Decode(&skip)
+	}
+
+	var got []string
+	for _, f := range jsonFieldsOf(reflect.TypeOf(jsonRecord{})).list {
+		got = append(got, f.name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fields = %v, encoding/json = %v", got, want)
+	}
+}
+
+func TestStrictAndSparseShareFields(t *testing.T) {
+	rt := NewRouter()
+	rt.Post("/records", func(w http.ResponseWriter, r *http.Request) {}).ValidateBody(&jsonRecord{}).Strict()
+	rt.Get("/records", func(resp *Response, req *Request) {
+		rec := jsonRecord{Title: "t"}
+		rec.jsonAudit.Note = "audit"
+		rec.jsonTagged.Note = "tagged"
+		resp.JSON(http.StatusOK, "", rec)
+	}).SparseFields()
+
+	// id is ambiguous at depth one, so encoding/json drops it
+	for body, wantCode := range map[string]int{
+		`{"title":"t","-":"d","Note":"n"}`: http.StatusOK,
+		`{"id":1}`:                         http.StatusUnprocessableEntity,
+		`{"Hidden":"h"}`:                   http.StatusUnprocessableEntity,
+	} {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(body)))
+		if w.Code != wantCode {
+			t.Fatalf("POST %s: status %d, want %d: %s", body, w.Code, wantCode, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/records?fields=id", nil))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("fields=id: status %d, want 422: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/records?fields=title,Note", nil))
+	if !strings.Contains(w.Body.String(), `"data":{"Note":"tagged","title":"t"}`) {
+		t.Fatalf("fields=title,Note: %s", w.Body.String())
+	}
+}
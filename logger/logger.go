@@ -114,7 +114,7 @@ func Info(msg string, f Fields) {
 	ensureStd()
 	e := std.Info()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
@@ -123,7 +123,7 @@ func Debug(msg string, f Fields) {
 	ensureStd()
 	e := std.Debug()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
@@ -132,7 +132,7 @@ func Warn(msg string, f Fields) {
 	ensureStd()
 	e := std.Warn()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
@@ -141,7 +141,7 @@ func Error(msg string, f Fields) {
 	ensureStd()
 	e := std.Error()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
@@ -160,7 +160,7 @@ func NewNop() *Logger {
 func (l *Logger) Info(msg string, f Fields) {
 	e := l.Z.Info()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
@@ -168,7 +168,7 @@ func (l *Logger) Info(msg string, f Fields) {
 func (l *Logger) Debug(msg string, f Fields) {
 	e := l.Z.Debug()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
@@ -176,7 +176,7 @@ func (l *Logger) Debug(msg string, f Fields) {
 func (l *Logger) Warn(msg string, f Fields) {
 	e := l.Z.Warn()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
@@ -184,7 +184,7 @@ func (l *Logger) Warn(msg string, f Fields) {
 func (l *Logger) Error(msg string, f Fields) {
 	e := l.Z.Error()
 	if f != nil {
-		e = e.Fields(f)
+		e = e.Fields(map[string]interface{}(f))
 	}
 	e.Msg(msg)
 }
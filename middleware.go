@@ -1,8 +1,11 @@
 package kyugo
 
 import (
+	"bufio"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	cfg "github.com/go-kyugo/kyugo/config"
@@ -49,6 +52,30 @@ func (r *responseRecorder) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Hijack lets WebSocket upgrades through the logger.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Flush lets streaming responses (SSE, NDJSON) through the logger.
+func (r *responseRecorder) Flush() {
+	_ = r.FlushError()
+}
+
+// FlushError flushes the wrapped writer, reporting writers that cannot.
+func (r *responseRecorder) FlushError() error {
+	return http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // Logger logs each HTTP request in a single, console-friendly line.
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -82,3 +109,48 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 		logger.Info(msg, nil)
 	})
 }
+
+// Recoverer returns a middleware that recovers from panics in downstream
+// handlers. The panic value and stack are logged through the logger package
+// and the client receives a 500 error envelope using the
+// `locale.internal_error` message. When debug is true (typically
+// `cfg.ConfigVar.App.Debug`) the envelope also carries the panic value and
+// stack under `error.meta`.
+func Recoverer(debugMode bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// let net/http abort the response as intended
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				rec, raw := recoveredPanic(rec)
+				stack := string(raw)
+				logger.Error("HTTP.Panic", logger.Fields{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"panic":  fmt.Sprint(rec),
+					"stack":  stack,
+				})
+
+				msg, ok := Message(r, "locale.internal_error")
+				if !ok || msg == "" {
+					msg = "Internal server error"
+				}
+				extras := ErrorExtras{Code: "INTERNAL_ERROR", Type: "PANIC"}
+				if debugMode {
+					extras.Meta = map[string]interface{}{
+						"panic": fmt.Sprint(rec),
+						"stack": strings.Split(strings.TrimSpace(stack), "\n"),
+					}
+				}
+				WriteError(w, r, http.StatusInternalServerError, msg, nil, extras)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package kyugo
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+// Pagination defaults used when neither the router nor the call configures
+// them.
+const (
+	DefaultPerPage = 20
+	DefaultMaxPage = 100
+)
+
+// ErrInvalidCursor is returned when decoding cursors that are
+// malformed or were not signed with the router's cursor key.
+var ErrInvalidCursor = errors.New("kyugo: invalid cursor")
+
+// processCursorKey signs cursors on routers without a cursor key. Being
+// random, it keeps cursors valid for the life of the process only.
+var processCursorKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// CursorKey sets the key signing the pagination cursors of the router's
+// routes; mounted routers inherit it. NewServer sets it from `app.secret`.
+// Cursors issued with another key are rejected, so instances sharing
+// clients need the same key. Without one a random per-process key is used.
+func (rt *Router) CursorKey(key []byte) *Router {
+	if len(key) > 0 {
+		rt.cursorKey = append([]byte(nil), key...)
+	}
+	return rt
+}
+
+// cursorKeyFor returns the cursor key of the router that matched r, or of
+// its closest ancestor, falling back to the per-process key.
+func cursorKeyFor(r *http.Request) []byte {
+	for rt := RouterFrom(r); rt != nil; rt = rt.parent {
+		if rt.cursorKey != nil {
+			return rt.cursorKey
+		}
+	}
+	return processCursorKey
+}
+
+// ErrWeakSecret is returned by NewServer when `app.secret` is a well-known
+// placeholder, which would let anyone forge signed cursors.
+var ErrWeakSecret = errors.New("kyugo: app.secret is a well-known placeholder; set a random value")
+
+var weakSecrets = map[string]bool{
+	"change-me": true, "changeme": true, "change_me": true, "secret": true,
+	"your-secret": true, "your_secret": true, "password": true, "test": true,
+}
+
+// checkAppSecret refuses placeholder secrets and warns about empty or
+// short ones. An empty secret keeps the random per-process cursor key.
+func checkAppSecret(secret string) error {
+	switch {
+	case weakSecrets[strings.ToLower(strings.TrimSpace(secret))]:
+		return ErrWeakSecret
+	case secret == "":
+		logger.Warn("app.secret is empty: pagination cursors are signed with a random key and stop working after a restart or on other instances", nil)
+	case len(secret) < 16:
+		logger.Warn("app.secret is shorter than 16 bytes: pagination cursors are weakly signed", nil)
+	}
+	return nil
+}
+
+// EncodeCursor returns an opaque cursor holding v encoded as JSON,
+// typically the sort key of the last item returned, signed with the
+// cursor key of the router serving the request.
+func (r *Request) EncodeCursor(v interface{}) (string, error) {
+	return encodeCursor(cursorKeyFor(r.R), v)
+}
+
+// DecodeCursor verifies cursor against the router's cursor key and decodes
+// its value into v.
+func (r *Request) DecodeCursor(cursor string, v interface{}) error {
+	return decodeCursor(cursorKeyFor(r.R), cursor, v)
+}
+
+func encodeCursor(key []byte, v interface{}) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signCursor(key, payload)), nil
+}
+
+func decodeCursor(key []byte, cursor string, v interface{}) error {
+	payload, err := verifyCursor(key, cursor)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func signCursor(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:16]
+}
+
+func verifyCursor(key []byte, cursor string) ([]byte, error) {
+	enc, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, signCursor(key, payload)) {
+		return nil, ErrInvalidCursor
+	}
+	return payload, nil
+}
+
+// PaginationOptions configures Request.Pagination.
+type PaginationOptions struct {
+	// DefaultPerPage applies when `per_page` is absent. Defaults to
+	// DefaultPerPage.
+	DefaultPerPage int
+	// MaxPerPage is the largest accepted `per_page`. Defaults to
+	// DefaultMaxPage.
+	MaxPerPage int
+}
+
+// Pagination sets the pagination defaults of the router's routes.
+// Mounted routers inherit them.
+func (rt *Router) Pagination(o PaginationOptions) *Router {
+	rt.pagination = &o
+	return rt
+}
+
+// paginationOptions returns the options of rt or its closest ancestor.
+func (rt *Router) paginationOptions() PaginationOptions {
+	for ; rt != nil; rt = rt.parent {
+		if rt.pagination != nil {
+			return *rt.pagination
+		}
+	}
+	return PaginationOptions{}
+}
+
+// Page holds the validated pagination parameters of a request: `page` and
+// `per_page` for offset pagination, or `cursor` and `per_page` for keyset
+// pagination.
+type Page struct {
+	// Number is the 1-based page number; it is 1 in cursor mode.
+	Number  int
+	PerPage int
+	// Offset is the number of items to skip, (Number-1)*PerPage.
+	Offset int
+	// Cursor is the verified cursor sent by the client, empty on the first
+	// page. Decode it with Page.DecodeCursor.
+	Cursor string
+
+	// key signs the cursors of the page; it is the request's cursor key.
+	key []byte
+}
+
+// Limit returns PerPage, for use in LIMIT clauses.
+func (p Page) Limit() int { return p.PerPage }
+
+// DecodeCursor decodes the value of the request cursor into v. It reports
+// false, leaving v alone, when the request carried no cursor.
+func (p Page) DecodeCursor(v interface{}) (bool, error) {
+	if p.Cursor == "" {
+		return false, nil
+	}
+	return true, decodeCursor(p.cursorKey(), p.Cursor, v)
+}
+
+func (p Page) cursorKey() []byte {
+	if p.key == nil {
+		return processCursorKey
+	}
+	return p.key
+}
+
+// WithTotal returns the PageInfo of an offset page whose collection holds
+// total items.
+func (p Page) WithTotal(total int64) PageInfo {
+	return PageInfo{Page: p, Total: &total}
+}
+
+// WithCursors returns the PageInfo of a cursor page, encoding next and prev
+// like Request.EncodeCursor. A nil value means there is no such page.
+func (p Page) WithCursors(next, prev interface{}) (PageInfo, error) {
+	info := PageInfo{Page: p, CursorMode: true}
+	var err error
+	if next != nil {
+		if info.NextCursor, err = encodeCursor(p.cursorKey(), next); err != nil {
+			return info, err
+		}
+	}
+	if prev != nil {
+		if info.PrevCursor, err = encodeCursor(p.cursorKey(), prev); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// Pagination reads and validates the `page`, `per_page` and `cursor` query
+// parameters using the router's pagination options, overridden by opts.
+// Invalid values yield a 422 *HTTPError with field errors that handlers
+// can return as is.
+//
+//	page, err := req.Pagination()
+//	if err != nil {
+//		return err
+//	}
+//	items, total, err := c.products.List(ctx, page.Limit(), page.Offset)
+//	...
+//	resp.Paginated(http.StatusOK, "", items, page.WithTotal(total))
+func (r *Request) Pagination(opts ...PaginationOptions) (Page, error) {
+	o := RouterFrom(r.R).paginationOptions()
+	if len(opts) > 0 {
+		if opts[0].DefaultPerPage > 0 {
+			o.DefaultPerPage = opts[0].DefaultPerPage
+		}
+		if opts[0].MaxPerPage > 0 {
+			o.MaxPerPage = opts[0].MaxPerPage
+		}
+	}
+	if o.DefaultPerPage <= 0 {
+		o.DefaultPerPage = DefaultPerPage
+	}
+	if o.MaxPerPage <= 0 {
+		o.MaxPerPage = DefaultMaxPage
+	}
+	if o.DefaultPerPage > o.MaxPerPage {
+		o.DefaultPerPage = o.MaxPerPage
+	}
+
+	q := r.R.URL.Query()
+	p := Page{Number: 1, PerPage: o.DefaultPerPage, key: cursorKeyFor(r.R)}
+	var fields []FieldError
+	intParam := func(name string, min, max int, dst *int) {
+		s := q.Get(name)
+		if s == "" {
+			return
+		}
+		n, err := strconv.Atoi(s)
+		switch {
+		case err != nil:
+			fields = append(fields, FieldError{Field: name, Code: "INVALID_NUMBER", Message: name + " must be an integer"})
+		case n < min:
+			fields = append(fields, FieldError{Field: name, Code: "INVALID_GTE|" + strconv.Itoa(min), Message: name + " must be at least " + strconv.Itoa(min)})
+		case max > 0 && n > max:
+			fields = append(fields, FieldError{Field: name, Code: "INVALID_LTE|" + strconv.Itoa(max), Message: name + " must be at most " + strconv.Itoa(max)})
+		default:
+			*dst = n
+		}
+	}
+	intParam("page", 1, 0, &p.Number)
+	intParam("per_page", 1, o.MaxPerPage, &p.PerPage)
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		if q.Get("page") != "" {
+			fields = append(fields, FieldError{Field: "cursor", Code: "INVALID_EXCLUDED_WITH|page", Message: "cursor cannot be combined with page"})
+		} else if _, err := verifyCursor(p.key, cursor); err != nil {
+			fields = append(fields, FieldError{Field: "cursor", Code: "INVALID_CURSOR", Message: "cursor is invalid"})
+		} else {
+			p.Cursor = cursor
+		}
+	}
+	if len(fields) > 0 {
+		return Page{}, ErrValidationFailed.WithFields(fields...)
+	}
+	p.Offset = (p.Number - 1) * p.PerPage
+	return p, nil
+}
+
+// PageInfo describes the page being returned by Response.Paginated. Build
+// it with Page.WithTotal or Page.WithCursors.
+type PageInfo struct {
+	Page Page
+	// Total is the size of the whole collection, when known.
+	Total *int64
+	// CursorMode selects the cursor meta and links even when both cursors
+	// are empty (the last page).
+	CursorMode bool
+	NextCursor string
+	PrevCursor string
+}
+
+// PageMeta is the `meta` block written by Response.Paginated.
+type PageMeta struct {
+	Total      *int64 `json:"total,omitempty" xml:"total,omitempty"`
+	Page       int    `json:"page,omitempty" xml:"page,omitempty"`
+	PerPage    int    `json:"per_page" xml:"per_page"`
+	TotalPages *int64 `json:"total_pages,omitempty" xml:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty" xml:"prev_cursor,omitempty"`
+}
+
+// Paginated writes items in a success envelope whose `meta` block
+// describes the page, and sets an RFC 8288 Link header pointing to the
+// first, previous, next and last pages as far as they are known. Links
+// reuse the current query string and are built with URLFor when the
+// route is named, so host routes get absolute links.
+//
+//	{"status":"success","code":200,"data":[...],"meta":{"total":42,"page":2,"per_page":20,"total_pages":3}}
+func (resp *Response) Paginated(status int, message string, items interface{}, info PageInfo) {
+	p := info.Page
+	if p.PerPage <= 0 {
+		p.PerPage = DefaultPerPage
+	}
+	if p.Number <= 0 {
+		p.Number = 1
+	}
+	meta := PageMeta{PerPage: p.PerPage, Total: info.Total}
+	var links []string
+	link := func(rel string, set map[string]string) {
+		links = append(links, "<"+pageURL(resp.R, set)+`>; rel="`+rel+`"`)
+	}
+	perPage := strconv.Itoa(p.PerPage)
+
+	if info.CursorMode || p.Cursor != "" || info.NextCursor != "" || info.PrevCursor != "" {
+		meta.NextCursor, meta.PrevCursor = info.NextCursor, info.PrevCursor
+		link("first", map[string]string{"cursor": "", "page": "", "per_page": perPage})
+		if info.PrevCursor != "" {
+			link("prev", map[string]string{"cursor": info.PrevCursor, "page": "", "per_page": perPage})
+		}
+		if info.NextCursor != "" {
+			link("next", map[string]string{"cursor": info.NextCursor, "page": "", "per_page": perPage})
+		}
+	} else {
+		meta.Page = p.Number
+		page := func(n int64) map[string]string {
+			return map[string]string{"page": strconv.FormatInt(n, 10), "per_page": perPage, "cursor": ""}
+		}
+		hasNext := lenOf(items) >= p.PerPage
+		var pages int64
+		if info.Total != nil {
+			pages = (*info.Total + int64(p.PerPage) - 1) / int64(p.PerPage)
+			meta.TotalPages = &pages
+			hasNext = int64(p.Number) < pages
+		}
+		link("first", page(1))
+		if p.Number > 1 {
+			link("prev", page(int64(p.Number-1)))
+		}
+		if hasNext {
+			link("next", page(int64(p.Number+1)))
+		}
+		if info.Total != nil {
+			link("last", page(max(pages, 1)))
+		}
+	}
+
+	if len(links) > 0 && resp.R != nil {
+		resp.W.Header().Set("Link", strings.Join(links, ", "))
+	}
+	writeSuccessEnvelope(resp.W, resp.R, SuccessEnvelope{Status: "success", Code: status, Message: message, Data: items, Meta: meta})
+}
+
+// pageURL returns the URL of the current route with the query parameters
+// in set replaced (or removed when empty).
+func pageURL(r *http.Request, set map[string]string) string {
+	if r == nil {
+		return ""
+	}
+	u := r.URL.Path
+	if rt := RouterFrom(r); rt != nil {
+		if key, ok := r.Context().Value(routeKeyKey).(string); ok {
+			if info, ok := rt.table.get(key); ok && info.name != "" {
+				params := map[string]string{}
+				for k, vv := range pathValues(r) {
+					params[k] = vv[0]
+				}
+				if built, ok := rt.URLFor(info.name, params); ok {
+					u = built
+				}
+			}
+		}
+	}
+	q := r.URL.Query()
+	for k, v := range set {
+		if v == "" {
+			q.Del(k)
+		} else {
+			q.Set(k, v)
+		}
+	}
+	if enc := q.Encode(); enc != "" {
+		u += "?" + enc
+	}
+	return u
+}
+
+// lenOf returns the length of slice or array values and -1 otherwise.
+func lenOf(v interface{}) int {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv.Len()
+	}
+	return -1
+}
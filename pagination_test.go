@@ -0,0 +1,125 @@
+package kyugo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// cursorRequest returns a Request routed through rt, so cursors are signed
+// with rt's key.
+func cursorRequest(t *testing.T, rt *Router, target string) *Request {
+	t.Helper()
+	var req *Request
+	rt.Get("/cursor", func(resp *Response, r *Request) { req = r })
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	if req == nil {
+		t.Fatal("route not served")
+	}
+	return req
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	req := cursorRequest(t, NewRouter().CursorKey([]byte("round-trip-key-0123456789")), "/cursor")
+	c, err := req.EncodeCursor(map[string]int{"id": 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]int
+	if err := req.DecodeCursor(c, &v); err != nil || v["id"] != 42 {
+		t.Fatalf("DecodeCursor = %v, %v", v, err)
+	}
+}
+
+func TestCursorTamperingIsRejected(t *testing.T) {
+	req := cursorRequest(t, NewRouter(), "/cursor")
+	c, err := req.EncodeCursor(map[string]int{"id": 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(c, ".")
+	forged, _ := req.EncodeCursor(map[string]int{"id": 1})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	for _, bad := range []string{
+		forgedPayload + "." + sig,
+		payload + "." + strings.Repeat("A", len(sig)),
+		payload,
+		"",
+		"not-base64!.x",
+	} {
+		var v map[string]int
+		if err := req.DecodeCursor(bad, &v); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestCursorKeysArePerRouter(t *testing.T) {
+	admin := NewRouter().CursorKey([]byte("admin-key-0123456789"))
+	public := NewRouter().CursorKey([]byte("public-key-0123456789"))
+	mounted := NewRouter()
+	admin.Mount("/sub", mounted)
+
+	c, _ := cursorRequest(t, admin, "/cursor").EncodeCursor(1)
+	var v int
+	if err := cursorRequest(t, public, "/cursor").DecodeCursor(c, &v); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("cursor signed by another router accepted: %v", err)
+	}
+	// configuring another router leaves the first one's cursors valid
+	NewRouter().CursorKey([]byte("third-key-0123456789"))
+	if err := cursorRequest(t, admin, "/cursor").DecodeCursor(c, &v); err != nil || v != 1 {
+		t.Fatalf("admin cursor no longer verifies: %v", err)
+	}
+	var sub *Request
+	mounted.Get("/cursor", func(resp *Response, r *Request) { sub = r })
+	admin.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sub/cursor", nil))
+	if sub == nil {
+		t.Fatal("mounted route not served")
+	}
+	if err := sub.DecodeCursor(c, &v); err != nil {
+		t.Fatalf("mounted router does not inherit the cursor key: %v", err)
+	}
+}
+
+func TestPaginationRejectsTamperedCursor(t *testing.T) {
+	rt := NewRouter().CursorKey([]byte("pagination-key-0123456789"))
+	c, _ := cursorRequest(t, rt, "/cursor").EncodeCursor(7)
+
+	_, err := cursorRequest(t, rt, "/cursor?cursor="+c+"x").Pagination()
+	var he *HTTPError
+	if !errors.As(err, &he) || he.Status != http.StatusUnprocessableEntity || he.Fields[0].Code != "INVALID_CURSOR" {
+		t.Fatalf("err = %v", err)
+	}
+
+	page, err := cursorRequest(t, rt, "/cursor?cursor="+c).Pagination()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if ok, err := page.DecodeCursor(&v); !ok || err != nil || v != 7 {
+		t.Fatalf("Page.DecodeCursor = %v, %v, %d", ok, err, v)
+	}
+	info, err := page.WithCursors(8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cursorRequest(t, rt, "/cursor").DecodeCursor(info.NextCursor, &v); err != nil || v != 8 {
+		t.Fatalf("next cursor = %v, %d", err, v)
+	}
+}
+
+func TestCheckAppSecret(t *testing.T) {
+	for _, s := range []string{"change-me", "CHANGEME", "secret"} {
+		if err := checkAppSecret(s); !errors.Is(err, ErrWeakSecret) {
+			t.Errorf("checkAppSecret(%q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "short", "7f3c1d2e9a8b4c5d6e7f8091a2b3c4d5"} {
+		if err := checkAppSecret(s); err != nil {
+			t.Errorf("checkAppSecret(%q) = %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package kyugo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func paramRequest(t *testing.T, pattern, target string) *Request {
+	t.Helper()
+	var req *Request
+	rt := NewRouter()
+	rt.Get(pattern, func(resp *Response, r *Request) { req = r })
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	if req == nil {
+		t.Fatalf("%s did not match %s", target, pattern)
+	}
+	return req
+}
+
+func TestParamInt(t *testing.T) {
+	req := paramRequest(t, "/items/{id}/{big}/{bad}", "/items/42/9007199254740993/x1")
+	if n, err := req.ParamInt("id"); err != nil || n != 42 {
+		t.Fatalf("ParamInt = %d, %v", n, err)
+	}
+	if n, err := req.ParamInt64("big"); err != nil || n != 9007199254740993 {
+		t.Fatalf("ParamInt64 = %d, %v", n, err)
+	}
+	if _, err := req.ParamInt("bad"); err == nil {
+		t.Fatal("ParamInt accepted x1")
+	}
+	if _, err := req.ParamInt64("missing"); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("ParamInt64(missing) = %v, want ErrMissingParam", err)
+	}
+}
+
+func TestParamUUID(t *testing.T) {
+	req := paramRequest(t, "/things/{id}/{bad}", "/things/6BA7B810-9DAD-11D1-80B4-00C04FD430C8/6ba7b810-9dad-11d1-80b4-00c04fd430c")
+	if id, err := req.ParamUUID("id"); err != nil || id != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Fatalf("ParamUUID = %q, %v", id, err)
+	}
+	if _, err := req.ParamUUID("bad"); err == nil {
+		t.Fatal("ParamUUID accepted a short UUID")
+	}
+	if _, err := req.ParamUUID("missing"); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("ParamUUID(missing) = %v, want ErrMissingParam", err)
+	}
+}
+
+func TestRouteRegexConstrainsParams(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/products/{productID:[0-9]+}", func(resp *Response, req *Request) {
+		resp.JSON(http.StatusOK, "", req.Param("productID"))
+	}).Name("products.show")
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/abc", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("/products/abc: status %d, want 404", w.Code)
+	}
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/12", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("/products/12: status %d", w.Code)
+	}
+
+	if routes := rt.Routes(); routes[0].Path != "/products/{productID:[0-9]+}" {
+		t.Fatalf("Routes()[0].Path = %q", routes[0].Path)
+	}
+	if u, ok := rt.URLFor("products.show", map[string]string{"productID": "12"}); !ok || u != "/products/12" {
+		t.Fatalf("URLFor = %q, %v", u, ok)
+	}
+}
+
+type productParams struct {
+	ProductID int `path:"productID" validate:"required,min=1"`
+}
+
+func TestValidateParams(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/products/{productID}", func(resp *Response, req *Request) {
+		p, _ := ParamsAsRequest[*productParams](req)
+		resp.JSON(http.StatusOK, "", p.ProductID)
+	}).ValidateParams(&productParams{})
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		code   string
+		field  string
+	}{
+		{"/products/7", http.StatusOK, "", ""},
+		{"/products/abc", http.StatusBadRequest, "INVALID_PARAMS", "productID"},
+		{"/products/0", http.StatusUnprocessableEntity, "INVALID_ATTRIBUTES", "productID"},
+	} {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d: %s", tc.path, w.Code, tc.status, w.Body.String())
+			continue
+		}
+		if tc.status == http.StatusOK {
+			continue
+		}
+		var env ErrorEnvelope
+		if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		if env.Error.Code != tc.code || len(env.Error.Fields) != 1 || env.Error.Fields[0].Field != tc.field {
+			t.Errorf("%s: error = %+v", tc.path, env.Error)
+		}
+	}
+}
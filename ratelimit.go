@@ -0,0 +1,391 @@
+package kyugo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+// RateLimitAlgorithm selects how a limiter counts requests.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket refills Limit tokens per Window up to Burst; each request
+	// takes one. It allows short bursts while enforcing the average rate.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows Limit requests in any Window, estimated from the
+	// counts of the current and previous fixed windows.
+	SlidingWindow
+)
+
+// RateLimitKeyFunc identifies the client a request counts against.
+// Requests for which it returns an empty key are not limited.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitOptions configures RateLimit, RouteChain.RateLimit and
+// Group.RateLimit.
+type RateLimitOptions struct {
+	// Limit is the number of requests allowed per Window. A zero or
+	// negative Limit disables the limiter.
+	Limit int
+	// Window defaults to one minute.
+	Window time.Duration
+	// Burst is the token bucket capacity. Defaults to Limit; ignored by
+	// SlidingWindow.
+	Burst     int
+	Algorithm RateLimitAlgorithm
+	// Key defaults to KeyByIP.
+	Key RateLimitKeyFunc
+	// Store defaults to an in-memory store owned by the limiter, so
+	// separate RateLimit calls (and routers) never share counters. Use a
+	// PostgresRateLimitStore to share limits between instances.
+	Store RateLimitStore
+	// Name namespaces the counters in a shared store: limiters with the
+	// same Name, Store and policy share their counters. RateLimit defaults
+	// it to "global", Group.RateLimit to the group prefix and
+	// RouteChain.RateLimit to the route.
+	Name string
+}
+
+// RateLimitPolicy is the part of RateLimitOptions a store applies.
+type RateLimitPolicy struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Burst     int
+	Window    time.Duration
+}
+
+// RateLimitResult is the outcome of counting one request.
+type RateLimitResult struct {
+	Allowed bool
+	// Limit is the request quota: the bucket capacity for TokenBucket.
+	Limit     int
+	Remaining int
+	// Reset is the time until the quota is fully available again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, set when
+	// Allowed is false.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the counters of rate limiters. Take counts one
+// request for key under p and must be safe for concurrent use.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, p RateLimitPolicy) (RateLimitResult, error)
+}
+
+// RateLimit returns a middleware limiting the request rate of each client
+// as configured by opts. Responses carry RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers; once
+// the quota is exhausted the client receives a 429 error envelope using
+// the `locale.too_many_requests` message and a Retry-After header. When
+// the store fails the error is logged and the request is let through.
+//
+//	srv, _ := kyugo.NewServer(kyugo.Options{DefaultMiddlewares: []func(http.Handler) http.Handler{
+//		kyugo.RateLimit(kyugo.RateLimitOptions{Limit: 600, Window: time.Minute}),
+//	}})
+func RateLimit(opts RateLimitOptions) func(http.Handler) http.Handler {
+	if opts.Name == "" {
+		opts.Name = "global"
+	}
+	if opts.Limit <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore()
+	}
+	// built once rather than per wrapped handler: route middleware is
+	// applied on every request, and the routes of a group share the quota
+	l := newRateLimiter(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.allow(w, r) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RateLimit limits the request rate of the previously registered route,
+// before its body is read or validated. See RateLimit for the responses.
+//
+//	r.Post("/login", ctrl.Login).RateLimit(kyugo.RateLimitOptions{Limit: 5, Window: time.Minute})
+func (rc *RouteChain) RateLimit(opts RateLimitOptions) *RouteChain {
+	if rc == nil || len(rc.keys) == 0 {
+		return rc
+	}
+	if opts.Name == "" {
+		opts.Name = rc.keys[0]
+	}
+	return rc.Middleware(RateLimit(opts))
+}
+
+// RateLimit limits the request rate of every route subsequently registered
+// through this group and its children. The routes share one quota per
+// client.
+func (g *Group) RateLimit(opts RateLimitOptions) *Group {
+	if opts.Name == "" {
+		opts.Name = "group " + g.host + g.prefix
+	}
+	mw := RateLimit(opts)
+	g.parent = g.parent.With(mw)
+	g.mws = append(g.mws, mw)
+	return g
+}
+
+// KeyByIP keys requests by the client IP taken from RemoteAddr. Put a
+// trusted real-IP middleware in front when running behind a proxy.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// KeyByPrincipal keys requests by the authenticated principal (see
+// WithPrincipal), falling back to the client IP for anonymous requests.
+func KeyByPrincipal(r *http.Request) string {
+	if id, ok := Principal(r); ok {
+		return "principal:" + id
+	}
+	return KeyByIP(r)
+}
+
+// KeyByAPIKey keys requests by the API key sent in header (X-API-Key when
+// empty), falling back to the client IP when it is missing. Keys are
+// hashed so stores never hold them in clear.
+func KeyByAPIKey(header string) RateLimitKeyFunc {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return func(r *http.Request) string {
+		key := r.Header.Get(header)
+		if key == "" {
+			return KeyByIP(r)
+		}
+		sum := sha256.Sum256([]byte(key))
+		return "apikey:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+type rateLimiter struct {
+	// name is the store namespace: the limiter Name and its policy, so
+	// limiters with different policies never share counters.
+	name   string
+	policy RateLimitPolicy
+	key    RateLimitKeyFunc
+	store  RateLimitStore
+}
+
+func newRateLimiter(opts RateLimitOptions) *rateLimiter {
+	l := &rateLimiter{
+		policy: RateLimitPolicy{Algorithm: opts.Algorithm, Limit: opts.Limit, Burst: opts.Burst, Window: opts.Window},
+		key:    opts.Key,
+		store:  opts.Store,
+	}
+	if l.policy.Window <= 0 {
+		l.policy.Window = time.Minute
+	}
+	if l.policy.Burst <= 0 || l.policy.Algorithm != TokenBucket {
+		l.policy.Burst = l.policy.Limit
+	}
+	if l.key == nil {
+		l.key = KeyByIP
+	}
+	l.name = opts.Name + ";" + l.policyHeader() + ";a=" + strconv.Itoa(int(l.policy.Algorithm))
+	return l
+}
+
+// allow counts r and writes the 429 response when it exceeds the limit.
+func (l *rateLimiter) allow(w http.ResponseWriter, r *http.Request) bool {
+	key := l.key(r)
+	if key == "" {
+		return true
+	}
+	res, err := l.store.Take(r.Context(), l.name+"|"+key, l.policy)
+	if err != nil {
+		logger.Error("HTTP.RateLimit", logger.Fields{
+			"limiter": l.name,
+			"path":    r.URL.Path,
+			"error":   err.Error(),
+		})
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+	h.Set("RateLimit-Policy", l.policyHeader())
+	if res.Allowed {
+		return true
+	}
+
+	retry := ceilSeconds(res.RetryAfter)
+	if retry < 1 {
+		retry = 1
+	}
+	h.Set("Retry-After", strconv.FormatInt(retry, 10))
+	msg, ok := Message(r, "locale.too_many_requests")
+	if !ok || msg == "" {
+		msg = "Too many requests"
+	}
+	WriteError(w, r, http.StatusTooManyRequests, msg, nil, ErrorExtras{
+		Code: "TOO_MANY_REQUESTS",
+		Type: "RATE_LIMITED",
+		Meta: map[string]interface{}{"limit": res.Limit, "retry_after": retry},
+	})
+	return false
+}
+
+// policyHeader describes the policy in RateLimit-Policy syntax.
+func (l *rateLimiter) policyHeader() string {
+	policy := strconv.Itoa(l.policy.Limit) + ";w=" + strconv.FormatInt(ceilSeconds(l.policy.Window), 10)
+	if l.policy.Burst != l.policy.Limit {
+		policy += ";burst=" + strconv.Itoa(l.policy.Burst)
+	}
+	return policy
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// rateState is the per-key state kept by the stores. For TokenBucket, a
+// holds the available tokens and at the last refill; for SlidingWindow, a
+// and b hold the counts of the previous and current windows and at the
+// start of the current one. A zero at marks a fresh key.
+type rateState struct {
+	a, b float64
+	at   time.Time
+}
+
+// take counts one request at now, updating s.
+func (p RateLimitPolicy) take(s *rateState, now time.Time) RateLimitResult {
+	if p.Algorithm == SlidingWindow {
+		return p.slide(s, now)
+	}
+	capacity := float64(p.Burst)
+	rate := float64(p.Limit) / p.Window.Seconds() // tokens per second
+	if s.at.IsZero() {
+		s.a, s.at = capacity, now
+	}
+	if elapsed := now.Sub(s.at).Seconds(); elapsed > 0 {
+		s.a = math.Min(capacity, s.a+elapsed*rate)
+		s.at = now
+	}
+
+	res := RateLimitResult{Limit: p.Burst}
+	if s.a >= 1 {
+		s.a--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - s.a) / rate)
+	}
+	res.Remaining = int(s.a)
+	res.Reset = seconds((capacity - s.a) / rate)
+	return res
+}
+
+func (p RateLimitPolicy) slide(s *rateState, now time.Time) RateLimitResult {
+	w := p.Window
+	if s.at.IsZero() {
+		s.at = now.Truncate(w)
+	}
+	if elapsed := now.Sub(s.at); elapsed >= w {
+		n := elapsed / w
+		s.a = s.b
+		if n > 1 {
+			s.a = 0
+		}
+		s.b = 0
+		s.at = s.at.Add(n * w)
+	}
+
+	elapsed := now.Sub(s.at)
+	limit := float64(p.Limit)
+	estimate := s.a*(1-float64(elapsed)/float64(w)) + s.b
+	res := RateLimitResult{Limit: p.Limit, Reset: w - elapsed}
+	switch {
+	case estimate+1 <= limit:
+		s.b++
+		estimate++
+		res.Allowed = true
+	case s.b+1 <= limit:
+		// the previous window still weighs too much; wait for it to fade
+		res.RetryAfter = time.Duration(float64(w)*(1-(limit-s.b-1)/s.a)) - elapsed
+	default:
+		// the current window is full; wait for the next one to fade it
+		res.RetryAfter = w - elapsed + time.Duration(float64(w)*(1-(limit-1)/s.b))
+	}
+	res.Remaining = int(math.Max(0, limit-estimate))
+	return res
+}
+
+// expires returns when s becomes indistinguishable from a fresh key.
+func (p RateLimitPolicy) expires(s rateState) time.Time {
+	if p.Algorithm == SlidingWindow {
+		return s.at.Add(2 * p.Window)
+	}
+	rate := float64(p.Limit) / p.Window.Seconds()
+	return s.at.Add(seconds((float64(p.Burst) - s.a) / rate))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// MemoryRateLimitStore is a RateLimitStore holding its counters in
+// process memory. Idle keys are dropped once their quota is restored.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryRateEntry
+	sweep   time.Time
+	now     func() time.Time
+}
+
+type memoryRateEntry struct {
+	state   rateState
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{entries: make(map[string]*memoryRateEntry), now: time.Now}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, p RateLimitPolicy) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.After(s.sweep) {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.sweep = now.Add(time.Minute)
+	}
+
+	e, ok := s.entries[key]
+	if !ok || now.After(e.expires) {
+		e = &memoryRateEntry{}
+		s.entries[key] = e
+	}
+	res := p.take(&e.state, now)
+	e.expires = p.expires(e.state)
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package kyugo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	database "github.com/go-kyugo/kyugo/database"
+)
+
+// DefaultRateLimitTable is the table used by PostgresRateLimitStore when no
+// name is given.
+const DefaultRateLimitTable = "kyugo_rate_limits"
+
+// PostgresRateLimitStore is a RateLimitStore keeping its counters in a
+// Postgres table, so every instance sharing the database shares the
+// limits. Each request locks its key's row for the duration of a short
+// transaction and the database clock is used, so instance clocks need not
+// agree. Create the table with Migrate and call Cleanup periodically to
+// drop idle keys.
+type PostgresRateLimitStore struct {
+	db    *database.DB
+	table string
+}
+
+// NewPostgresRateLimitStore returns a store using table (DefaultRateLimitTable
+// when empty) in db.
+func NewPostgresRateLimitStore(db *database.DB, table string) *PostgresRateLimitStore {
+	if table == "" {
+		table = DefaultRateLimitTable
+	}
+	return &PostgresRateLimitStore{db: db, table: pq.QuoteIdentifier(table)}
+}
+
+// Migrate creates the table when it does not exist.
+func (s *PostgresRateLimitStore) Migrate(ctx context.Context) error {
+	_, err := s.db.SQL.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.table+` (
+	key TEXT PRIMARY KEY,
+	a DOUBLE PRECISION NOT NULL DEFAULT 0,
+	b DOUBLE PRECISION NOT NULL DEFAULT 0,
+	stamp TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ NOT NULL
+)`)
+	return err
+}
+
+// Cleanup deletes the keys whose quota is fully restored and returns how
+// many were removed.
+func (s *PostgresRateLimitStore) Cleanup(ctx context.Context) (int64, error) {
+	res, err := s.db.SQL.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Take implements RateLimitStore.
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, p RateLimitPolicy) (res RateLimitResult, err error) {
+	if s == nil || s.db == nil || s.db.SQL == nil {
+		return res, errors.New("kyugo: rate limit store has no database")
+	}
+	tx, err := s.db.SQL.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `INSERT INTO `+s.table+` (key, expires_at) VALUES ($1, now()) ON CONFLICT (key) DO NOTHING`, key); err != nil {
+		return res, fmt.Errorf("kyugo: rate limit insert: %w", err)
+	}
+	var st rateState
+	var at, expires, now sql.NullTime
+	row := tx.QueryRowContext(ctx, `SELECT a, b, stamp, expires_at, now() FROM `+s.table+` WHERE key = $1 FOR UPDATE`, key)
+	if err = row.Scan(&st.a, &st.b, &at, &expires, &now); err != nil {
+		return res, fmt.Errorf("kyugo: rate limit select: %w", err)
+	}
+	if at.Valid && !now.Time.After(expires.Time) {
+		st.at = at.Time
+	} else {
+		st = rateState{}
+	}
+
+	res = p.take(&st, now.Time)
+	if _, err = tx.ExecContext(ctx, `UPDATE `+s.table+` SET a = $2, b = $3, stamp = $4, expires_at = $5 WHERE key = $1`,
+		key, st.a, st.b, st.at, p.expires(st)); err != nil {
+		return res, fmt.Errorf("kyugo: rate limit update: %w", err)
+	}
+	err = tx.Commit()
+	return res, err
+}
@@ -0,0 +1,94 @@
+package kyugo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func okHandler(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+func get(h http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestRateLimitRejectsOverQuota(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/a", okHandler).RateLimit(RateLimitOptions{Limit: 2, Window: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if w := get(rt, "/a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	w := get(rt, "/a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("missing rate limit headers: %v", w.Header())
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+		t.Fatalf("RateLimit-Policy = %q", got)
+	}
+}
+
+func TestRateLimitRoutersDoNotShareQuotas(t *testing.T) {
+	a, b := NewRouter(), NewRouter()
+	a.Get("/a", okHandler).RateLimit(RateLimitOptions{Limit: 2})
+	b.Get("/a", okHandler).RateLimit(RateLimitOptions{Limit: 2})
+
+	get(a, "/a")
+	get(a, "/a")
+	if w := get(b, "/a"); w.Code != http.StatusOK {
+		t.Fatalf("second router limited by the first: %d", w.Code)
+	}
+}
+
+func TestRateLimitMiddlewaresDoNotShareQuotas(t *testing.T) {
+	strict := RateLimit(RateLimitOptions{Limit: 1})(http.HandlerFunc(okHandler))
+	loose := RateLimit(RateLimitOptions{Limit: 10})(http.HandlerFunc(okHandler))
+
+	get(strict, "/")
+	if w := get(loose, "/"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "9" {
+		t.Fatalf("loose limiter = %d remaining %s", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+func TestGroupRateLimitSharesQuota(t *testing.T) {
+	rt := NewRouter()
+	g := rt.Group("/api").RateLimit(RateLimitOptions{Limit: 1})
+	g.Get("/a", okHandler)
+	g.Get("/b", okHandler)
+
+	if w := get(rt, "/api/a"); w.Code != http.StatusOK {
+		t.Fatalf("first request: %d", w.Code)
+	}
+	if w := get(rt, "/api/b"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("group routes do not share the quota: %d", w.Code)
+	}
+}
+
+func TestMemoryStoreTokenBucketRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryRateLimitStore()
+	s.now = func() time.Time { return now }
+	p := RateLimitPolicy{Algorithm: TokenBucket, Limit: 2, Burst: 2, Window: time.Second}
+
+	for i := 0; i < 2; i++ {
+		if res, _ := s.Take(context.Background(), "k", p); !res.Allowed {
+			t.Fatalf("take %d rejected", i)
+		}
+	}
+	if res, _ := s.Take(context.Background(), "k", p); res.Allowed {
+		t.Fatal("bucket not exhausted")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if res, _ := s.Take(context.Background(), "k", p); !res.Allowed {
+		t.Fatal("bucket did not refill")
+	}
+}
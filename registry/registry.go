@@ -6,18 +6,75 @@ import (
 )
 
 var (
-	mu       sync.RWMutex
-	handlers = make(map[string]http.HandlerFunc)
+	mu         sync.RWMutex
+	handlers   = make(map[string]interface{})
+	dtos       = make(map[string]interface{})
+	middleware = make(map[string]func(http.Handler) http.Handler)
 )
 
 func Register(name string, h http.HandlerFunc) {
+	RegisterHandler(name, h)
+}
+
+// RegisterHandler stores a handler of any shape accepted by the kyugo
+// router (for example func(*kyugo.Response, *kyugo.Request)) under name.
+func RegisterHandler(name string, h interface{}) {
 	mu.Lock()
 	defer mu.Unlock()
 	handlers[name] = h
 }
 
+// Get returns the handler registered under name as an http.HandlerFunc,
+// or nil when it is missing or has another shape (see Handler).
 func Get(name string) http.HandlerFunc {
 	mu.RLock()
 	defer mu.RUnlock()
-	return handlers[name]
+	switch h := handlers[name].(type) {
+	case http.HandlerFunc:
+		return h
+	case func(http.ResponseWriter, *http.Request):
+		return h
+	case http.Handler:
+		return h.ServeHTTP
+	}
+	return nil
+}
+
+// Handler returns the handler registered under name as it was stored.
+func Handler(name string) (interface{}, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	h, ok := handlers[name]
+	return h, ok
+}
+
+// RegisterDTO stores an example DTO value (for example &dto.CreateProduct{})
+// under name so declarative routes can refer to it.
+func RegisterDTO(name string, v interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	dtos[name] = v
+}
+
+// DTO returns the example value registered under name.
+func DTO(name string) (interface{}, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	v, ok := dtos[name]
+	return v, ok
+}
+
+// RegisterMiddleware stores a middleware under name.
+func RegisterMiddleware(name string, mw func(http.Handler) http.Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	middleware[name] = mw
+}
+
+// Middleware returns the middleware registered under name.
+func Middleware(name string) (func(http.Handler) http.Handler, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	mw, ok := middleware[name]
+	return mw, ok && mw != nil
 }
@@ -1,13 +1,20 @@
 package kyugo
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
 
+const principalKey ctxKey = "youu.principal"
+
 // Request is a small wrapper around *http.Request providing convenience
 // methods used by handlers in the codebase.
 type Request struct {
@@ -27,6 +34,76 @@ func (r *Request) Param(name string) string {
 	return chi.URLParam(r.R, name)
 }
 
+// ErrMissingParam is returned by the typed param accessors when the named
+// URL parameter is absent or empty.
+var ErrMissingParam = errors.New("missing path parameter")
+
+// ParamInt returns the named URL parameter parsed as an int.
+func (r *Request) ParamInt(name string) (int, error) {
+	n, err := r.paramInt(name, strconv.IntSize)
+	return int(n), err
+}
+
+// ParamInt64 returns the named URL parameter parsed as an int64.
+func (r *Request) ParamInt64(name string) (int64, error) {
+	return r.paramInt(name, 64)
+}
+
+func (r *Request) paramInt(name string, bits int) (int64, error) {
+	s := r.Param(name)
+	if s == "" {
+		return 0, fmt.Errorf("%w: %s", ErrMissingParam, name)
+	}
+	n, err := strconv.ParseInt(s, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// ParamUUID returns the named URL parameter when it is a valid UUID in its
+// canonical 8-4-4-4-12 hex form. The result is lower-cased.
+func (r *Request) ParamUUID(name string) (string, error) {
+	s := r.Param(name)
+	if s == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingParam, name)
+	}
+	if !isUUID(s) {
+		return "", fmt.Errorf("param %s: invalid UUID %q", name, s)
+	}
+	return strings.ToLower(s), nil
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParamsAsRequest is the Request counterpart of ParamsAs and returns the
+// validated path DTO stored by the router's ValidateParams step.
+func ParamsAsRequest[T any](r *Request) (T, bool) {
+	var zero T
+	if r == nil || r.R == nil {
+		return zero, false
+	}
+	return ParamsAs[T](r.R)
+}
+
 // BodyAsRequest is a generic helper that attempts to retrieve the validated
 // body previously stored by the router's validation step and assert it to T.
 func BodyAsRequest[T any](r *Request) (T, bool) {
@@ -37,6 +114,16 @@ func BodyAsRequest[T any](r *Request) (T, bool) {
 	return BodyAs[T](r.R)
 }
 
+// QueryAsRequest is the Request counterpart of QueryAs and returns the
+// validated query DTO stored by the router's ValidateQuery step.
+func QueryAsRequest[T any](r *Request) (T, bool) {
+	var zero T
+	if r == nil || r.R == nil {
+		return zero, false
+	}
+	return QueryAs[T](r.R)
+}
+
 // Message looks up an application message (localization) for the request.
 func (r *Request) Message(key string) (string, bool) {
 	return Message(r.R, key)
@@ -52,6 +139,29 @@ func (r *Request) BindJSON(v any) error {
 	return dec.Decode(v)
 }
 
+// Bind decodes the request body into v with the codec registered for the
+// request Content-Type (JSON when the header is absent). It returns
+// ErrUnsupportedMediaType when no codec matches.
+func (r *Request) Bind(v any) error {
+	if r == nil || r.R == nil {
+		return errors.New("nil request")
+	}
+	b, err := io.ReadAll(r.R.Body)
+	if err != nil {
+		return err
+	}
+	return decodeBody(r.R, b, v)
+}
+
+// URLFor builds a path for a named route registered on the router that
+// dispatched this request. See Router.URLFor.
+func (r *Request) URLFor(name string, params map[string]string) (string, bool) {
+	if r == nil || r.R == nil {
+		return "", false
+	}
+	return RouterFrom(r.R).URLFor(name, params)
+}
+
 // Method returns the HTTP method.
 func (r *Request) Method() string {
 	if r == nil || r.R == nil {
@@ -75,3 +185,28 @@ func (r *Request) RemoteAddr() string {
 	}
 	return r.R.RemoteAddr
 }
+
+// WithPrincipal returns a copy of r carrying the identifier of the
+// authenticated principal (a user or client ID). Authentication middleware
+// calls it so later steps such as KeyByPrincipal can see who is calling.
+func WithPrincipal(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey, id))
+}
+
+// Principal returns the principal stored with WithPrincipal.
+func Principal(r *http.Request) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	id, ok := r.Context().Value(principalKey).(string)
+	return id, ok && id != ""
+}
+
+// Principal returns the authenticated principal of the request. See
+// WithPrincipal.
+func (r *Request) Principal() (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	return Principal(r.R)
+}
@@ -1,15 +1,24 @@
 package kyugo
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"path"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 var loaded = make(map[string]map[string]string)
 
+// resourcesLoadedAt is reported as the modification time of files served
+// from ResourcesFS.
+var resourcesLoadedAt time.Time
+
 // Resources holds raw file contents for any file under the resources
 // directory. Keys are the relative file paths (unix-style) as provided by
 // the FS passed to LoadResources.
@@ -47,6 +56,8 @@ func LoadResources(fsys fs.FS) error {
 	if fsys == nil {
 		return fmt.Errorf("nil fs provided")
 	}
+	resourcesLoadedAt = time.Now()
+	defer resetResourceDirs()
 	// walk the FS and load every file
 	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -112,3 +123,146 @@ func GetResource(p string) ([]byte, bool) {
 	}
 	return nil, false
 }
+
+// ResourcesFS returns the files loaded by LoadResources as an fs.FS, for
+// example to serve them with Router.Static. Directories are derived from
+// the file paths.
+func ResourcesFS() fs.FS {
+	return resourceFS{}
+}
+
+type resourceFS struct{}
+
+func (resourceFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if b, ok := Resources[name]; ok {
+		info := resourceInfo{name: path.Base(name), size: int64(len(b))}
+		return &resourceFile{Reader: bytes.NewReader(b), info: info}, nil
+	}
+	entries, ok := resourceDirEntries(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &resourceDir{info: resourceInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+// resourceDirs indexes the directories derived from the Resources paths so
+// opening a directory, or a missing name, does not scan every file. It is
+// rebuilt after LoadResources or when the number of resources changed.
+var resourceDirs struct {
+	mu    sync.Mutex
+	count int
+	dirs  map[string][]fs.DirEntry
+}
+
+func resetResourceDirs() {
+	resourceDirs.mu.Lock()
+	resourceDirs.dirs = nil
+	resourceDirs.mu.Unlock()
+}
+
+// resourceDirEntries returns the sorted entries of directory name and
+// whether it exists. The root always exists.
+func resourceDirEntries(name string) ([]fs.DirEntry, bool) {
+	resourceDirs.mu.Lock()
+	defer resourceDirs.mu.Unlock()
+	if resourceDirs.dirs == nil || resourceDirs.count != len(Resources) {
+		resourceDirs.dirs, resourceDirs.count = indexResourceDirs(), len(Resources)
+	}
+	entries, ok := resourceDirs.dirs[name]
+	if !ok && name == "." {
+		return nil, true
+	}
+	// copy so concurrent readers can page through their own slice
+	return append([]fs.DirEntry(nil), entries...), ok
+}
+
+func indexResourceDirs() map[string][]fs.DirEntry {
+	dirs := map[string][]fs.DirEntry{}
+	seen := map[string]bool{}
+	for k, b := range Resources {
+		dir, child := path.Split(k)
+		info := resourceInfo{name: child, size: int64(len(b))}
+		for {
+			dir = strings.TrimSuffix(dir, "/")
+			if dir == "" {
+				dir = "."
+			}
+			if key := dir + "\x00" + info.name; !seen[key] {
+				seen[key] = true
+				dirs[dir] = append(dirs[dir], info)
+			}
+			if dir == "." {
+				break
+			}
+			parent, name := path.Split(dir)
+			dir, info = parent, resourceInfo{name: name, dir: true}
+		}
+	}
+	for _, entries := range dirs {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	}
+	return dirs
+}
+
+// resourceInfo describes a resource file or derived directory. It serves
+// as both fs.FileInfo and fs.DirEntry.
+type resourceInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i resourceInfo) Name() string               { return i.name }
+func (i resourceInfo) Size() int64                { return i.size }
+func (i resourceInfo) ModTime() time.Time         { return resourcesLoadedAt }
+func (i resourceInfo) IsDir() bool                { return i.dir }
+func (i resourceInfo) Sys() interface{}           { return nil }
+func (i resourceInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i resourceInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+func (i resourceInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+type resourceFile struct {
+	*bytes.Reader
+	info resourceInfo
+}
+
+func (f *resourceFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *resourceFile) Close() error               { return nil }
+
+type resourceDir struct {
+	info    resourceInfo
+	entries []fs.DirEntry
+	off     int
+}
+
+func (d *resourceDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *resourceDir) Close() error               { return nil }
+
+func (d *resourceDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *resourceDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.off:]
+	if n <= 0 {
+		d.off = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.off += n
+	return rest[:n], nil
+}
@@ -1,47 +1,61 @@
 package kyugo
 
 import (
-	"encoding/json"
+	"bytes"
+	"encoding/xml"
 	"fmt"
 	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
+
+	logger "github.com/go-kyugo/kyugo/logger"
 )
 
 type ErrorDetail struct {
-	Field   string `json:"field,omitempty"`
-	Code    string `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
+	Field   string `json:"field,omitempty" xml:"field,attr,omitempty"`
+	Code    string `json:"code,omitempty" xml:"code,attr,omitempty"`
+	Message string `json:"message,omitempty" xml:",chardata"`
 }
 
 // ErrorBody defines the structure inside the top-level `error` key. Field
 // ordering here matters for JSON output: Code, Fields (optional), Message, Type.
 type ErrorBody struct {
-	Type    string        `json:"type"`
-	Code    string        `json:"code"`
-	Message string        `json:"message"`
-	Fields  []ErrorDetail `json:"fields,omitempty"`
-	Meta    interface{}   `json:"meta,omitempty"`
+	Type    string        `json:"type" xml:"type"`
+	Code    string        `json:"code" xml:"code"`
+	Message string        `json:"message" xml:"message"`
+	Fields  []ErrorDetail `json:"fields,omitempty" xml:"field,omitempty"`
+	Meta    interface{}   `json:"meta,omitempty" xml:"meta,omitempty"`
 }
 
+// ErrorEnvelope and SuccessEnvelope are both rendered as a <response>
+// element when XML is negotiated.
 type ErrorEnvelope struct {
-	Status string    `json:"status"`
-	Code   int       `json:"code"`
-	Error  ErrorBody `json:"error"`
+	XMLName xml.Name  `json:"-" xml:"response"`
+	Status  string    `json:"status" xml:"status"`
+	Code    int       `json:"code" xml:"code"`
+	Error   ErrorBody `json:"error" xml:"error"`
 }
 
 type SuccessEnvelope struct {
-	Status  string      `json:"status"`
-	Code    int         `json:"code,omitempty"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data"`
+	XMLName xml.Name    `json:"-" xml:"response"`
+	Status  string      `json:"status" xml:"status"`
+	Code    int         `json:"code,omitempty" xml:"code,omitempty"`
+	Message string      `json:"message,omitempty" xml:"message,omitempty"`
+	Data    interface{} `json:"data" xml:"data"`
+	// Meta carries response metadata such as pagination (see
+	// Response.Paginated).
+	Meta interface{} `json:"meta,omitempty" xml:"meta,omitempty"`
 }
 
 type ErrorExtras struct {
 	Code string
 	Type string
+	// Meta is rendered as `error.meta` when non-nil.
+	Meta interface{}
 }
 
 type Response struct {
@@ -54,16 +68,17 @@ func NewResponse(w http.ResponseWriter, r *http.Request) *Response {
 	return &Response{W: w, R: r}
 }
 
-// JSON writes a JSON response with the given status.
+// JSON writes an envelope with the given status. Despite its name the
+// body is encoded with the codec negotiated from the request's Accept
+// header (see WriteSuccess); JSON remains the default.
 func (resp *Response) JSON(status int, message string, v interface{}, extras ...ErrorExtras) {
-	resp.W.Header().Set("Content-Type", "application/json")
 	if status >= 200 && status < 300 {
-		SuccessResponse(resp.W, status, message, v)
+		WriteSuccess(resp.W, resp.R, status, message, v)
 		return
 	}
 	// For non-2xx statuses use the Error envelope with a default HTTP code/message.
 
-	ErrorResponse(resp.W, status, message, nil, extras...)
+	WriteError(resp.W, resp.R, status, message, nil, extras...)
 }
 
 // ServeFile serves a file from disk using http.ServeFile. Helpful for
@@ -80,6 +95,11 @@ func (resp *Response) ServeFile(filePath string) error {
 			ct = http.DetectContentType(b)
 		}
 		resp.W.Header().Set("Content-Type", ct)
+		if resp.R != nil {
+			// honours Range and If-Modified-Since
+			http.ServeContent(resp.W, resp.R, path.Base(filePath), resourcesLoadedAt, bytes.NewReader(b))
+			return nil
+		}
 		resp.W.WriteHeader(http.StatusOK)
 		_, _ = resp.W.Write(b)
 		return nil
@@ -144,17 +164,135 @@ func (resp *Response) WriteDBError(err error) bool {
 	if err == nil {
 		return false
 	}
-	ErrorResponse(resp.W, http.StatusInternalServerError, "internal_error", nil, ErrorExtras{
+	WriteError(resp.W, resp.R, http.StatusInternalServerError, "internal_error", nil, ErrorExtras{
 		Code: "DB_ERROR",
 		Type: "DATABASE_ERROR",
 	})
 	return true
 }
 
+// SuccessResponse writes a JSON success envelope. Use WriteSuccess to
+// honour the request's Accept header.
 func SuccessResponse(w http.ResponseWriter, code int, message string, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(SuccessEnvelope{Status: "success", Code: code, Message: message, Data: data})
+	WriteSuccess(w, nil, code, message, data)
+}
+
+// WriteSuccess writes a success envelope encoded with the codec negotiated
+// from r's Accept header. When r is nil the envelope is JSON; when no
+// registered codec is acceptable the client receives a 406 error envelope
+// instead.
+func WriteSuccess(w http.ResponseWriter, r *http.Request, code int, message string, data interface{}) {
+	writeSuccessEnvelope(w, r, SuccessEnvelope{Status: "success", Code: code, Message: message, Data: data})
+}
+
+func writeSuccessEnvelope(w http.ResponseWriter, r *http.Request, env SuccessEnvelope) {
+	mt, c := MediaTypeJSON, Codec(jsonCodec{})
+	if r != nil {
+		var ok bool
+		if mt, c, ok = negotiate(r); !ok {
+			writeNotAcceptable(w, r)
+			return
+		}
+		addVary(w.Header(), "Accept")
+	}
+	if sw := sparseWriterOf(w); sw != nil {
+		if !sw.prune(&env) {
+			return
+		}
+	}
+	writeEnvelope(w, r, mt, c, http.StatusOK, env)
+}
+
+// writeEnvelope encodes env with c and writes it. When c cannot represent
+// env the encode error is logged and the envelope is written as JSON if r
+// accepts JSON; otherwise a 500 error envelope is written instead. Error
+// envelopes always fall back to JSON.
+func writeEnvelope(w http.ResponseWriter, r *http.Request, mediaType string, c Codec, status int, env interface{}) {
+	b, err := c.Marshal(env)
+	if err != nil && mediaType != MediaTypeJSON {
+		logger.Error("HTTP.Encode", logger.Fields{"media_type": mediaType, "error": err.Error()})
+		if _, isError := env.(ErrorEnvelope); !isError && !acceptsJSON(r) {
+			writeEncodeFailed(w, r)
+			return
+		}
+		mediaType = MediaTypeJSON
+		b, err = jsonCodec{}.Marshal(env)
+	}
+	if err != nil {
+		logger.Error("HTTP.Encode", logger.Fields{"media_type": mediaType, "error": err.Error()})
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+// addVary adds name to the Vary header unless it is listed already.
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+// acceptsJSON reports whether r has no Accept header or one accepting
+// JSON.
+func acceptsJSON(r *http.Request) bool {
+	if r == nil {
+		return true
+	}
+	header := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		return true
+	}
+	q, _ := acceptQuality(parseAccept(header), MediaTypeJSON)
+	return q > 0
+}
+
+// writeEncodeFailed writes the 500 envelope used when a success envelope
+// cannot be encoded in the negotiated format.
+func writeEncodeFailed(w http.ResponseWriter, r *http.Request) {
+	msg, ok := Message(r, "locale.internal_error")
+	if !ok || msg == "" {
+		msg = "Internal server error"
+	}
+	WriteError(w, r, http.StatusInternalServerError, msg, nil, ErrorExtras{
+		Code: "INTERNAL_ERROR",
+		Type: "ENCODE_ERROR",
+	})
+}
+
+// writeNotAcceptable writes the 406 envelope used when the Accept header
+// matches no registered codec. The envelope itself is JSON.
+func writeNotAcceptable(w http.ResponseWriter, r *http.Request) {
+	msg, ok := Message(r, "locale.not_acceptable")
+	if !ok || msg == "" {
+		msg = "Not acceptable"
+	}
+	WriteError(w, r, http.StatusNotAcceptable, msg, nil, ErrorExtras{
+		Code: "NOT_ACCEPTABLE",
+		Type: "UNSUPPORTED_ACCEPT",
+		Meta: map[string]interface{}{"supported": supportedMediaTypes()},
+	})
+}
+
+// writeUnsupportedMediaType writes the 415 envelope used when the request
+// Content-Type is not one of supported.
+func writeUnsupportedMediaType(w http.ResponseWriter, r *http.Request, supported []string) {
+	msg, ok := Message(r, "locale.unsupported_media_type")
+	if !ok || msg == "" {
+		msg = "Unsupported media type"
+	}
+	WriteError(w, r, http.StatusUnsupportedMediaType, msg, nil, ErrorExtras{
+		Code: "UNSUPPORTED_MEDIA_TYPE",
+		Type: "INVALID_CONTENT_TYPE",
+		Meta: map[string]interface{}{"supported": supported},
+	})
 }
 
 // convertDetails attempts to turn various detail types into []ErrorDetail.
@@ -203,11 +341,16 @@ func convertDetails(details interface{}) []ErrorDetail {
 }
 
 // Error builds a consistent error envelope. When `details` are provided they
-// are included under `error.fields`; otherwise that key is omitted.
+// are included under `error.fields`; otherwise that key is omitted. The
+// envelope is JSON; use WriteError to honour the request's Accept header.
 func ErrorResponse(w http.ResponseWriter, code int, message string, details interface{}, extras ...ErrorExtras) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
+	WriteError(w, nil, code, message, details, extras...)
+}
 
+// WriteError is ErrorResponse encoded with the codec negotiated from r's
+// Accept header. Errors are never turned into a 406: when nothing is
+// acceptable, or r is nil, the envelope is JSON.
+func WriteError(w http.ResponseWriter, r *http.Request, code int, message string, details interface{}, extras ...ErrorExtras) {
 	eb := ErrorBody{Code: "", Message: message, Type: ""}
 	// If at least one extras entry is provided, use its values as overrides.
 	// Map ErrorExtras.Code -> ErrorBody.Type and ErrorExtras.Type -> ErrorBody.Code
@@ -218,6 +361,7 @@ func ErrorResponse(w http.ResponseWriter, code int, message string, details inte
 		if s := extras[0].Type; s != "" {
 			eb.Code = s
 		}
+		eb.Meta = extras[0].Meta
 	}
 	// If a second extras entry is provided, it can override the first.
 	if len(extras) > 1 {
@@ -227,6 +371,9 @@ func ErrorResponse(w http.ResponseWriter, code int, message string, details inte
 		if s := extras[1].Type; s != "" {
 			eb.Code = s
 		}
+		if extras[1].Meta != nil {
+			eb.Meta = extras[1].Meta
+		}
 	}
 	if d := convertDetails(details); len(d) > 0 {
 		eb.Fields = d
@@ -235,5 +382,12 @@ func ErrorResponse(w http.ResponseWriter, code int, message string, details inte
 	// error `code` and `type` in that order: extras[0] -> code, extras[1] -> type.
 
 	env := ErrorEnvelope{Status: "error", Code: code, Error: eb}
-	_ = json.NewEncoder(w).Encode(env)
+	mt, c := MediaTypeJSON, Codec(jsonCodec{})
+	if r != nil {
+		if m, cc, ok := negotiate(r); ok {
+			mt, c = m, cc
+		}
+		addVary(w.Header(), "Accept")
+	}
+	writeEnvelope(w, r, mt, c, code, env)
 }
@@ -3,18 +3,19 @@ package kyugo
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
 	"reflect"
-	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	cfg "github.com/go-kyugo/kyugo/config"
 	"github.com/go-kyugo/kyugo/registry"
 )
 
@@ -24,24 +25,26 @@ type routeEntry struct {
 	handlerName string
 }
 
-var (
-	mu     sync.RWMutex
-	routes []routeEntry
-	// name maps: allow naming routes for reverse lookups
-	nameMu    sync.RWMutex
-	nameToKey = make(map[string]string) // name -> key (METHOD path)
-	keyToPath = make(map[string]string) // key -> cleaned path template
-	// validateBodyMap maps route keys to an optional DTO type to validate.
-	validateBodyMu  sync.RWMutex
-	validateBodyMap = make(map[string]reflect.Type)
-	// middlewareMap maps route keys to a slice of middleware to apply.
-	middlewareMu  sync.RWMutex
-	middlewareMap = make(map[string][]func(http.Handler) http.Handler)
-)
+// defaultRouting holds the default router and the package-level named
+// routes queued until one is set.
+type defaultRouting struct {
+	mu      sync.RWMutex
+	rt      *Router
+	pending []routeEntry
+}
+
+var defaults = &defaultRouting{}
 
 type ctxKey string
 
-const validatedBodyKey ctxKey = "youu.validated_body"
+const (
+	validatedBodyKey   ctxKey = "youu.validated_body"
+	validatedQueryKey  ctxKey = "youu.validated_query"
+	validatedParamsKey ctxKey = "youu.validated_params"
+	routerKey          ctxKey = "youu.router"
+	routeKeyKey        ctxKey = "youu.route"
+	httpRequestKey     ctxKey = "youu.http_request"
+)
 
 // ContextKey is a key type exported for storing values in request context
 // used by the router (for example messages injected by the server).
@@ -52,23 +55,123 @@ type ContextKey string
 var MessagesKey ContextKey = "youu.messages"
 
 // RegisterHandlerName keeps compatibility with generated code that registers
-// handlers by name and resolves them from the runtime registry.
+// handlers by name and resolves them from the runtime registry. Routes
+// added this way belong to the default router: they are installed by
+// SetDefaultRouter, or immediately when a default router is already set.
+// Use Router.RegisterHandlerName to target a specific router.
 func RegisterHandlerName(method, p, handlerName string) {
-	mu.Lock()
-	routes = append(routes, routeEntry{method: method, path: p, handlerName: handlerName})
-	mu.Unlock()
+	defaults.register(method, p, handlerName)
+}
+
+// SetDefaultRouter sets the router used by the package-level URLFor and
+// installs the routes queued by the package-level RegisterHandlerName on
+// it. NewServer makes the first router it creates the default; processes
+// running several servers (say an admin and a public one) call
+// SetDefaultRouter to pick another, or use Router.URLFor directly.
+func SetDefaultRouter(rt *Router) {
+	defaults.set(rt, false)
+}
+
+// DefaultRouter returns the router set by SetDefaultRouter, or nil.
+func DefaultRouter() *Router {
+	return defaults.router()
+}
+
+func (d *defaultRouting) register(method, p, handlerName string) {
+	d.mu.Lock()
+	rt := d.rt
+	if rt == nil {
+		d.pending = append(d.pending, routeEntry{method: method, path: p, handlerName: handlerName})
+	}
+	d.mu.Unlock()
+	if rt != nil {
+		rt.RegisterHandlerName(method, p, handlerName)
+	}
+}
+
+// set makes rt the default router, unless onlyIfUnset is true and one is
+// already set, and installs the queued routes on it. It reports whether
+// rt became the default.
+func (d *defaultRouting) set(rt *Router, onlyIfUnset bool) bool {
+	d.mu.Lock()
+	if onlyIfUnset && d.rt != nil {
+		d.mu.Unlock()
+		return false
+	}
+	d.rt = rt
+	var pending []routeEntry
+	if rt != nil {
+		pending, d.pending = d.pending, nil
+	}
+	d.mu.Unlock()
+	for _, re := range pending {
+		rt.RegisterHandlerName(re.method, re.path, re.handlerName)
+	}
+	return true
+}
+
+func (d *defaultRouting) router() *Router {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.rt
 }
 
 // Router is a lightweight wrapper around an underlying chi router that
-// exposes a small, fluent API similar to the example you provided.
+// exposes a small, fluent API similar to the example you provided. Route
+// metadata (validation, middleware, names) is owned by each Router so
+// several routers can live in one process without sharing rules.
 type Router struct {
 	r      chi.Router
 	server *Server
+	table  *routeTable
+	// mounts lists sub-routers mounted with Mount so URLFor can resolve
+	// their named routes; parent/base are set on the mounted router.
+	mounts []*Router
+	parent *Router
+	base   string
+	// errorHandler renders errors returned by handlers; nil falls back to
+	// the parent router and then DefaultErrorHandler.
+	errorHandler ErrorHandler
+	// notFoundHandler and methodNotAllowedHandler override the default
+	// envelopes; nil falls back to the parent router.
+	notFoundHandler         http.HandlerFunc
+	methodNotAllowedHandler http.HandlerFunc
+	// versioning is set by Versioning; versions records the versions
+	// declared with Version.
+	versioning *VersioningOptions
+	versions   map[string]bool
+	// maxUploadSize is the default body limit of ValidateForm routes and
+	// maxBodySize that of every other route (see MaxBodySize).
+	maxUploadSize int64
+	maxBodySize   int64
+	strictJSON    bool
+	// hosts holds the host-bound route trees created with Host, tried in
+	// order before the host-independent routes.
+	hosts     []*hostRoutes
+	urlScheme string
+	// cors drives the origin check of WebSocket routes.
+	cors       *cfg.CorsConfig
+	pagination *PaginationOptions
+	cursorKey  []byte
+}
+
+// AnyMethods lists the methods registered by Any.
+var AnyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
 }
 
 // New creates a new Router instance.
 func NewRouter() *Router {
-	return &Router{r: chi.NewRouter()}
+	rt := &Router{r: chi.NewRouter(), table: newRouteTable(), versions: make(map[string]bool)}
+	rt.r.NotFound(rt.notFound)
+	rt.r.MethodNotAllowed(rt.methodNotAllowed)
+	return rt
 }
 
 // Registrer is implemented by controllers/components that need to be
@@ -107,58 +210,323 @@ func (rt *Router) Delete(p string, h interface{}, mws ...func(http.Handler) http
 	return rt.Group("/").Delete(p, h, mws...)
 }
 
+func (rt *Router) Put(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return rt.Group("/").Put(p, h, mws...)
+}
+
+func (rt *Router) Head(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return rt.Group("/").Head(p, h, mws...)
+}
+
+func (rt *Router) Options(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return rt.Group("/").Options(p, h, mws...)
+}
+
+func (rt *Router) Any(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return rt.Group("/").Any(p, h, mws...)
+}
+
+func (rt *Router) Match(methods []string, p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return rt.Group("/").Match(methods, p, h, mws...)
+}
+
+// NotFound sets the handler for requests that match no route. The default
+// writes a 404 error envelope using the `locale.not_found` message. h
+// accepts the same handler shapes as route registration.
+func (rt *Router) NotFound(h interface{}) *Router {
+	rt.notFoundHandler = mustHandler(h, "NotFound")
+	return rt
+}
+
+// MethodNotAllowed sets the handler for requests whose path matches a
+// route but whose method does not. The Allow header is already set when
+// h runs. The default writes a 405 error envelope using the
+// `locale.method_not_allowed` message.
+func (rt *Router) MethodNotAllowed(h interface{}) *Router {
+	rt.methodNotAllowedHandler = mustHandler(h, "MethodNotAllowed")
+	return rt
+}
+
+// mustHandler converts h with handlerToHTTP and panics naming the hook
+// when its type is unsupported.
+func mustHandler(h interface{}, hook string) http.HandlerFunc {
+	hf, err := handlerToHTTP(h)
+	if err != nil {
+		panic(fmt.Sprintf("kyugo: %s: %v", hook, err))
+	}
+	return hf
+}
+
+// notFound dispatches to the first NotFound hook found walking up the
+// mount chain, or writes the default envelope.
+func (rt *Router) notFound(w http.ResponseWriter, r *http.Request) {
+	for p := rt; p != nil; p = p.parent {
+		if p.notFoundHandler != nil {
+			p.notFoundHandler(w, r)
+			return
+		}
+	}
+	msg, ok := Message(r, "locale.not_found")
+	if !ok || msg == "" {
+		msg = "Resource not found"
+	}
+	WriteError(w, r, http.StatusNotFound, msg, nil, ErrorExtras{
+		Code: "NOT_FOUND",
+		Type: "ROUTE_NOT_FOUND",
+	})
+}
+
+// methodNotAllowed answers requests whose path matches a route but whose
+// method does not. OPTIONS requests receive a 204 listing the allowed
+// methods; anything else is passed to the MethodNotAllowed hook or gets a
+// localized 405 error envelope. In both cases the Allow header is
+// populated from the route table.
+func (rt *Router) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	rt.methodNotAllowedIn(rt.r, w, r)
+}
+
+// methodNotAllowedIn is methodNotAllowed with the Allow header computed
+// from the routes of mux.
+func (rt *Router) methodNotAllowedIn(mux chi.Router, w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+	for _, m := range AnyMethods {
+		if mux.Match(chi.NewRouteContext(), m, routingPath(r)) {
+			allowed = append(allowed, m)
+		}
+	}
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+	for p := rt; p != nil; p = p.parent {
+		if p.methodNotAllowedHandler != nil {
+			p.methodNotAllowedHandler(w, r)
+			return
+		}
+	}
+	msg, ok := Message(r, "locale.method_not_allowed")
+	if !ok || msg == "" {
+		msg = "Method not allowed"
+	}
+	WriteError(w, r, http.StatusMethodNotAllowed, msg, nil, ErrorExtras{
+		Code: "METHOD_NOT_ALLOWED",
+		Type: "INVALID_METHOD",
+	})
+}
+
 // Handler returns the underlying http.Handler to be used with ListenAndServe.
 func (rt *Router) Handler() http.Handler {
-	// Register any routes previously added via RegisterHandlerName
-	mu.RLock()
-	defer mu.RUnlock()
-
-	for _, re := range routes {
-		method := strings.ToUpper(re.method)
-		p := re.path
-		name := re.handlerName
-
-		if strings.HasPrefix(name, "missing:") {
-			rt.r.Method(method, p, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-				http.NotFound(w, &http.Request{})
-			}))
-			continue
-		}
+	return http.HandlerFunc(rt.serve)
+}
 
-		rt.r.Method(method, p, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			if h := registry.Get(name); h != nil {
-				h(w, req)
-				return
-			}
-			http.NotFound(w, req)
-		}))
+// serve resolves the API version (when Versioning is enabled) and then
+// dispatches to the host trees and the chi router, so host routes are
+// versioned like the others.
+func (rt *Router) serve(w http.ResponseWriter, r *http.Request) {
+	if rt.versioning != nil {
+		r = rt.routeVersion(r)
+	}
+	if len(rt.hosts) > 0 && rt.serveHost(w, r) {
+		return
 	}
+	rt.r.ServeHTTP(w, r)
+}
+
+// ErrorHandler sets the handler used to render errors returned by
+// handlers registered on this router and its mounted sub-routers.
+func (rt *Router) ErrorHandler(h ErrorHandler) *Router {
+	rt.errorHandler = h
+	return rt
+}
 
-	return rt.r
+// ServeHTTP implements http.Handler so a Router can be served or mounted
+// directly.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.Handler().ServeHTTP(w, r)
+}
+
+// RegisterHandlerName registers a route on this router whose handler is
+// resolved by name from the runtime registry at request time. Use
+// LoadRoutes to resolve names up front instead.
+func (rt *Router) RegisterHandlerName(method, p, handlerName string) *RouteChain {
+	if strings.HasPrefix(handlerName, "missing:") {
+		return rt.Match([]string{method}, p, http.HandlerFunc(http.NotFound))
+	}
+	return rt.Match([]string{method}, p, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if h, ok := registry.Handler(handlerName); ok {
+			if hf, err := handlerToHTTP(h); err == nil {
+				hf(w, req)
+				return
+			}
+		}
+		http.NotFound(w, req)
+	}))
 }
 
 // Group creates a route group rooted at the provided prefix.
 func (rt *Router) Group(prefix string) *Group {
-	return &Group{parent: rt.r, prefix: prefix}
+	return &Group{parent: rt.r, prefix: prefix, router: rt}
+}
+
+// Route creates a group rooted at prefix and passes it to fn so related
+// routes can be declared in a closure. It returns the created group.
+func (rt *Router) Route(prefix string, fn func(*Group)) *Group {
+	return rt.Group("/").Route(prefix, fn)
+}
+
+// Mount attaches h under prefix. When h is a *Router its named routes stay
+// resolvable through this router's URLFor (prefixed accordingly) and it
+// inherits the server when it has none. Other handlers receive the full
+// request path; chi based handlers see the remaining path as their route.
+func (rt *Router) Mount(prefix string, h http.Handler) {
+	rt.Group("/").Mount(prefix, h)
+}
+
+// Routes returns every route registered on this router, followed by the
+// routes of mounted sub-routers, with full path templates.
+func (rt *Router) Routes() []RouteInfo {
+	if rt == nil {
+		return nil
+	}
+	out := rt.table.list(rt.prefix())
+	for _, sub := range rt.mounts {
+		out = append(out, sub.Routes()...)
+	}
+	return out
+}
+
+// RoutesHandler returns a handler that writes Routes as a success envelope.
+func (rt *Router) RoutesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		WriteSuccess(w, r, http.StatusOK, "", rt.Routes())
+	}
+}
+
+// mount records sub as mounted under prefix.
+func (rt *Router) mount(prefix string, sub *Router) {
+	sub.parent = rt
+	sub.base = strings.TrimSuffix(prefix, "/")
+	if sub.server == nil {
+		sub.server = rt.server
+	}
+	rt.mounts = append(rt.mounts, sub)
+}
+
+// prefix returns the full path prefix under which rt is mounted.
+func (rt *Router) prefix() string {
+	if rt.parent == nil {
+		return rt.base
+	}
+	return rt.parent.prefix() + rt.base
 }
 
-// Group represents a group of routes under a common prefix.
+// Group represents a group of routes under a common prefix. Groups can be
+// nested; children inherit the prefix, middleware, route name prefix and
+// group-level params validation of their parent.
 type Group struct {
-	parent chi.Router
-	prefix string
+	parent     chi.Router
+	prefix     string
+	router     *Router
+	namePrefix string
+	params     reflect.Type
+	timeout    time.Duration
+	version    string
+	deprecated bool
+	sunset     time.Time
+	// host is the host pattern of groups created with Router.Host.
+	host string
+	// mws records middleware applied through Use/With for introspection.
+	mws []func(http.Handler) http.Handler
+}
+
+// derive returns a copy of g using parent as the underlying chi router.
+func (g *Group) derive(parent chi.Router, prefix string) *Group {
+	return &Group{
+		parent:     parent,
+		prefix:     prefix,
+		router:     g.router,
+		namePrefix: g.namePrefix,
+		params:     g.params,
+		timeout:    g.timeout,
+		version:    g.version,
+		deprecated: g.deprecated,
+		sunset:     g.sunset,
+		host:       g.host,
+		mws:        append([]func(http.Handler) http.Handler(nil), g.mws...),
+	}
+}
+
+// Group creates a child group under prefix. Middleware added to the child
+// with Use does not leak to the parent or its siblings.
+func (g *Group) Group(prefix string) *Group {
+	return g.derive(g.parent.With(), join(g.prefix, prefix))
+}
+
+// Route creates a child group under prefix and passes it to fn:
+//
+//	api.Route("/tenants/{id}", func(t *kyugo.Group) {
+//		t.Get("/users", ctrl.Users)
+//	})
+func (g *Group) Route(prefix string, fn func(*Group)) *Group {
+	child := g.Group(prefix)
+	if fn != nil {
+		fn(child)
+	}
+	return child
+}
+
+// Mount attaches h under the group's prefix joined with prefix. See
+// Router.Mount.
+func (g *Group) Mount(prefix string, h http.Handler) {
+	full := join(g.prefix, prefix)
+	if sub, ok := h.(*Router); ok {
+		g.router.mount(full, sub)
+		h = sub.Handler()
+	}
+	g.parent.Mount(full, h)
+}
+
+// Name sets a prefix prepended to route names registered through this
+// group and its children, e.g. `g.Name("admin.")` then `.Name("users")`
+// registers "admin.users".
+func (g *Group) Name(prefix string) *Group {
+	g.namePrefix += prefix
+	return g
+}
+
+// ValidateParams registers a path params DTO for every route subsequently
+// registered through this group and its children. Routes can override it
+// with RouteChain.ValidateParams.
+func (g *Group) ValidateParams(dto interface{}) *Group {
+	g.params = typeOf(dto)
+	return g
+}
+
+// Timeout sets a deadline for every route subsequently registered through
+// this group and its children. Routes can override it with
+// RouteChain.Timeout. See the Timeout middleware for the behaviour.
+func (g *Group) Timeout(d time.Duration) *Group {
+	g.timeout = d
+	return g
 }
 
 // With returns a new Group that applies the provided middleware to all
 // routes registered through it. This mirrors chi's `With` behaviour and
 // allows `router.Group("/x").With(mw).Get(...)` usage.
 func (g *Group) With(mws ...func(http.Handler) http.Handler) *Group {
-	return &Group{parent: g.parent.With(mws...), prefix: g.prefix}
+	child := g.derive(g.parent.With(mws...), g.prefix)
+	child.mws = append(child.mws, mws...)
+	return child
 }
 
 // Use applies middleware to the group's parent router in-place and returns
 // the same group for chaining. This mirrors chi's `Use` behaviour.
 func (g *Group) Use(mws ...func(http.Handler) http.Handler) *Group {
 	g.parent.Use(mws...)
+	g.mws = append(g.mws, mws...)
 	return g
 }
 
@@ -175,31 +543,49 @@ func join(prefix, p string) string {
 	return path.Join(prefix, p)
 }
 
-// RouteChain provides chainable validation methods after registering a route.
-// They are no-ops in this lightweight implementation but preserve the fluent API.
+// RouteChain provides chainable configuration methods (validation,
+// middleware, naming) after registering a route. Routes registered for
+// several methods at once (Any, Match) share a single chain.
 type RouteChain struct {
-	key string
+	table      *routeTable
+	keys       []string
+	namePrefix string
+}
+
+func (rc *RouteChain) update(fn func(*route)) *RouteChain {
+	if rc == nil || rc.table == nil {
+		return rc
+	}
+	rc.table.update(rc.keys, fn)
+	return rc
 }
 
-// ValidateQuery is a no-op that preserves the example fluent API.
-func (rc *RouteChain) ValidateQuery(_ interface{}) *RouteChain { return rc }
+// ValidateQuery registers a DTO value used to bind and validate the query
+// string of the previously registered route. Fields are matched using the
+// `query:"..."` tag (falling back to the json tag); slice fields collect
+// repeated keys. The bound value is available to handlers via QueryAs.
+// Passing nil disables query validation for the route.
+func (rc *RouteChain) ValidateQuery(dto interface{}) *RouteChain {
+	return rc.update(func(r *route) { r.query = typeOf(dto) })
+}
+
+// ValidateParams registers a DTO value used to bind and validate the URL
+// path parameters of the previously registered route. Fields are matched
+// using the `path:"..."` tag (falling back to the json tag). The bound value
+// is available to handlers via ParamsAs. Passing nil disables the step.
+func (rc *RouteChain) ValidateParams(dto interface{}) *RouteChain {
+	return rc.update(func(r *route) { r.params = typeOf(dto) })
+}
 
 // ValidateBody registers an optional DTO value for the previously registered
 // route. If `dto` is nil we only check that the request body is valid JSON.
 // If `dto` is a non-nil example value, the router will attempt to unmarshal
 // the body into a fresh instance of that type and run `validation.Validate`.
 func (rc *RouteChain) ValidateBody(dto interface{}) *RouteChain {
-	if rc == nil || rc.key == "" {
-		return rc
-	}
-	var t reflect.Type
-	if dto != nil {
-		t = reflect.TypeOf(dto)
-	}
-	validateBodyMu.Lock()
-	validateBodyMap[rc.key] = t
-	validateBodyMu.Unlock()
-	return rc
+	return rc.update(func(r *route) {
+		r.hasBody = true
+		r.body = typeOf(dto)
+	})
 }
 
 // Middleware registers middleware for the previously-registered route.
@@ -208,252 +594,426 @@ func (rc *RouteChain) ValidateBody(dto interface{}) *RouteChain {
 //
 //	group.Post(...).ValidateBody(...).Middleware(mw1, mw2)
 func (rc *RouteChain) Middleware(mws ...func(http.Handler) http.Handler) *RouteChain {
-	if rc == nil || rc.key == "" {
-		return rc
-	}
-	middlewareMu.Lock()
-	middlewareMap[rc.key] = append(middlewareMap[rc.key], mws...)
-	middlewareMu.Unlock()
-	return rc
+	return rc.update(func(r *route) { r.middleware = append(r.middleware, mws...) })
+}
+
+// Timeout puts a deadline of d on the request context of the previously
+// registered route, covering its middleware, validation and handler. When
+// the handler overruns, a 503 error envelope is written and late writes
+// are discarded. A zero duration disables a timeout inherited from the
+// group.
+func (rc *RouteChain) Timeout(d time.Duration) *RouteChain {
+	return rc.update(func(r *route) { r.timeout = d })
 }
 
 // Name assigns a stable name to the previously-registered route so it can
 // be looked up for reverse URL generation. Call it like:
 //
 //	r.Get("/users/{id}", handler).Name("user.show")
+//
+// For multi-method routes the name resolves to the first method; all of
+// them share the same path template.
 func (rc *RouteChain) Name(name string) *RouteChain {
-	if rc == nil || rc.key == "" || name == "" {
+	if rc == nil || rc.table == nil || len(rc.keys) == 0 || name == "" {
 		return rc
 	}
-	nameMu.Lock()
-	nameToKey[name] = rc.key
-	nameMu.Unlock()
+	rc.table.setName(rc.namePrefix+name, rc.keys[0])
 	return rc
 }
 
-// convert handler provided by caller to an http.HandlerFunc. Supported types:
-// - http.HandlerFunc
-// - func(*Response, *Request)
-func handlerToHTTP(h interface{}) http.HandlerFunc {
-	switch v := h.(type) {
-	case http.HandlerFunc:
-		return v
-	case func(*Response, *Request):
-		return Adapt(v)
-	default:
-		return func(w http.ResponseWriter, r *http.Request) {
-			http.NotFound(w, r)
-		}
+// typeOf returns the dynamic type of v, or nil when v is nil.
+func typeOf(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
 	}
+	return reflect.TypeOf(v)
 }
 
-// helper to register and return a RouteChain
-func registerAndChain(parent chi.Router, method, p string, h interface{}) *RouteChain {
-	// convert {name:regex} -> {name} so chi matches the route
-	re := regexp.MustCompile(`\{([a-zA-Z0-9_]+):[^}]+\}`)
-	cleaned := re.ReplaceAllString(p, `{$1}`)
-
-	key := strings.ToUpper(method) + " " + cleaned
-
-	// store cleaned path for potential reverse lookups
-	nameMu.Lock()
-	keyToPath[key] = cleaned
-	nameMu.Unlock()
+// register adds a single METHOD + path route to parent, records it in the
+// router's route table and returns its key. The installed handler runs the
+// route's middleware, then the configured validation steps, then h.
+func (rt *Router) register(parent chi.Router, host, method, p string, h interface{}, mws []func(http.Handler) http.Handler) string {
+	info := &route{
+		method:          method,
+		path:            p,
+		host:            host,
+		handler:         funcName(h),
+		groupMiddleware: funcNames(mws),
+	}
+	if th, ok := h.(*TypedHandler); ok {
+		info.handler = th.name
+		info.req = th.Req
+		info.res = th.Res
+	}
+	hf, err := handlerToHTTP(h)
+	if err != nil {
+		panic(fmt.Sprintf("kyugo: route %s %s: %v", strings.ToUpper(method), p, err))
+	}
+	key := rt.table.add(info)
 
-	hf := handlerToHTTP(h)
+	parent.Method(strings.ToUpper(method), p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := rt.table.get(key)
+		ctx := context.WithValue(r.Context(), routerKey, rt)
+		r = r.WithContext(context.WithValue(ctx, routeKeyKey, key))
+		r = setVersionHeaders(w, r, info)
 
-	parent.Method(strings.ToUpper(method), cleaned, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// baseHandler performs body validation (if configured) and then
-		// invokes the actual handler `h`.
+		// baseHandler performs params, query and body validation (if
+		// configured) and then invokes the actual handler `h`.
 		baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// check if this route has a body validation rule
-			validateBodyMu.RLock()
-			t, ok := validateBodyMap[key]
-			validateBodyMu.RUnlock()
-			if ok {
-				// read entire body and restore later so handler can read it too
-				b, err := io.ReadAll(r.Body)
-				if err != nil {
-					http.Error(w, "Failed to read body", http.StatusInternalServerError)
+			limit := info.maxBodySize
+			if limit == 0 && info.form != nil {
+				limit = rt.uploadLimit()
+			}
+			if limit == 0 {
+				limit = rt.bodyLimit()
+			}
+			if !limitBody(w, r, limit) {
+				return
+			}
+			if info.strict || rt.strict() {
+				r = r.WithContext(context.WithValue(r.Context(), strictJSONKey, true))
+			}
+			var ok bool
+			if r, ok = validateParamsStep(w, r, info.params); !ok {
+				return
+			}
+			if r, ok = validateQueryStep(w, r, info.query); !ok {
+				return
+			}
+			if r, ok = validateFormStep(w, r, info.form); !ok {
+				return
+			}
+			if r.MultipartForm != nil {
+				defer r.MultipartForm.RemoveAll()
+			}
+			if info.hasBody {
+				if r, ok = validateBodyStep(w, r, info.body); !ok {
 					return
 				}
-				// syntax check JSON
-				var tmp interface{}
-				if len(b) == 0 {
-					// empty body is invalid JSON for endpoints expecting a body
-					msg, ok := Message(r, "locale.invalid_body")
-					if !ok {
-						msg = "Invalid JSON body"
-					}
-					ErrorResponse(w, http.StatusBadRequest, msg, nil, ErrorExtras{
-						Code: "INVALID_REQUEST",
-						Type: "INVALID_BODY",
-					})
-					return
+			}
+			if info.sparse {
+				fields := info.fields
+				if fields == nil {
+					fields = info.res
 				}
-				if err := json.Unmarshal(b, &tmp); err != nil {
-					msg, ok := Message(r, "locale.invalid_body")
-					if !ok {
-						msg = "Invalid JSON body"
-					}
-					ErrorResponse(w, http.StatusBadRequest, msg, nil, ErrorExtras{
-						Code: "INVALID_REQUEST",
-						Type: "INVALID_BODY",
-					})
+				if w, ok = sparseFieldsStep(w, r, fields); !ok {
 					return
 				}
-
-				// if a concrete DTO type was provided, unmarshal into it and run validation
-				if t != nil {
-					var v interface{}
-					if t.Kind() == reflect.Ptr {
-						v = reflect.New(t.Elem()).Interface()
-					} else {
-						v = reflect.New(t).Interface()
-					}
-					if err := json.Unmarshal(b, v); err != nil {
-						msg, ok := Message(r, "locale.invalid_body")
-						if !ok {
-							msg = "Invalid JSON body"
-						}
-						ErrorResponse(w, http.StatusBadRequest, msg, nil, ErrorExtras{
-							Code: "INVALID_REQUEST",
-							Type: "INVALID_BODY",
-						})
-						return
-					}
-					if err := Validate(v); err != nil {
-						fields := FormatValidationErrors(err, v)
-
-						// localize each field message using resources with precedence:
-						// 1. fields.<field>.<rule>
-						// 2. rules.<rule>
-						// 3. fields.<field>
-						for i := range fields {
-							fe := &fields[i]
-							// parse rule and param from Code: "invalid_<rule>|<param>"
-							// parse rule and param from Code. supported formats:
-							// "INVALID_<RULE>|<param>", "invalid_<Rule>", "RULE", "RULE|param"
-							code := fe.Code
-							param := ""
-							parts := strings.SplitN(code, "|", 2)
-							main := parts[0]
-							if len(parts) > 1 {
-								param = parts[1]
-							}
-							// strip optional "INVALID_" prefix (case-insensitive)
-							if strings.HasPrefix(strings.ToUpper(main), "INVALID_") {
-								main = main[len("INVALID_"):]
-							}
-							rule := strings.ToLower(main)
-							upper := strings.ToUpper(code)
-							if strings.HasPrefix(upper, "INVALID_") {
-								rest := code[len("INVALID_"):]
-								parts := strings.SplitN(rest, "|", 2)
-								rule = strings.ToLower(parts[0])
-								if len(parts) > 1 {
-									param = parts[1]
-								}
-							}
-							var msgStr string
-							keysToTry := []string{
-								fmt.Sprintf("fields.%s.%s", strings.ToLower(fe.Field), rule),
-								fmt.Sprintf("rules.%s", rule),
-								fmt.Sprintf("fields.%s", strings.ToLower(fe.Field)),
-							}
-							for _, k := range keysToTry {
-								if s, ok := Message(r, k); ok && s != "" {
-									msgStr = s
-									break
-								}
-							}
-							if msgStr == "" {
-								if fe.Message != "" {
-									msgStr = fe.Message
-								} else {
-									msgStr = "Invalid value"
-								}
-							}
-							fieldLabel := fe.Field
-							if s, ok := Message(r, fmt.Sprintf("fields.%s", strings.ToLower(fe.Field))); ok && s != "" {
-								fieldLabel = s
-							}
-							msgStr = strings.ReplaceAll(msgStr, "{field}", fieldLabel)
-							msgStr = strings.ReplaceAll(msgStr, "{param}", param)
-							fe.Message = msgStr
-						}
-
-						msg, ok := Message(r, "locale.validation_failed")
-						if !ok || msg == "" {
-							msg = "Validation failed"
-						}
-						ErrorResponse(w, http.StatusUnprocessableEntity, msg, fields, ErrorExtras{
-							Code: "VALIDATION_ERROR",
-							Type: "INVALID_ATTRIBUTES",
-						})
-						return
-					}
-					// store validated value in request context for handler use
-					ctx := context.WithValue(r.Context(), validatedBodyKey, v)
-					r = r.WithContext(ctx)
-				}
-
-				// restore body for downstream handlers
-				r.Body = io.NopCloser(bytes.NewReader(b))
 			}
-
 			hf(w, r)
 		})
 
-		// fetch any middleware registered for this route and wrap the base
-		// handler. Middleware should run before the validation/handler.
-		middlewareMu.RLock()
-		mws := middlewareMap[key]
-		middlewareMu.RUnlock()
-
+		// wrap the base handler with any middleware registered for this
+		// route. Middleware should run before the validation/handler.
 		final := http.Handler(baseHandler)
-		for i := len(mws) - 1; i >= 0; i-- {
-			final = mws[i](final)
+		for i := len(info.middleware) - 1; i >= 0; i-- {
+			final = info.middleware[i](final)
+		}
+		if info.timeout > 0 {
+			final = Timeout(info.timeout)(final)
 		}
 
 		final.ServeHTTP(w, r)
 	}))
-	return &RouteChain{key: key}
+	return key
+}
+
+// validateParamsStep binds the chi URL params of the matched route into the
+// DTO type t registered with ValidateParams and validates it. Values that
+// cannot be converted produce a 400; validation failures produce the usual
+// 422.
+func validateParamsStep(w http.ResponseWriter, r *http.Request, t reflect.Type) (*http.Request, bool) {
+	if t == nil {
+		return r, true
+	}
+
+	v := newDTO(t)
+	if fields := bindValues(pathValues(r), "path", v); len(fields) > 0 {
+		writeInvalidParams(w, r, fields)
+		return r, false
+	}
+	if err := Validate(v); err != nil {
+		writeValidationFailed(w, r, formatValidationErrors(err, v, "path"))
+		return r, false
+	}
+	ctx := context.WithValue(r.Context(), validatedParamsKey, v)
+	return r.WithContext(ctx), true
+}
+
+// validateQueryStep binds and validates the query string into the DTO type
+// t registered with ValidateQuery. It
+// returns the request carrying the validated value and false when an error
+// response has already been written.
+func validateQueryStep(w http.ResponseWriter, r *http.Request, t reflect.Type) (*http.Request, bool) {
+	if t == nil {
+		return r, true
+	}
+
+	v := newDTO(t)
+	if fields := bindValues(r.URL.Query(), "query", v); len(fields) > 0 {
+		writeValidationFailed(w, r, fields)
+		return r, false
+	}
+	if err := Validate(v); err != nil {
+		writeValidationFailed(w, r, formatValidationErrors(err, v, "query"))
+		return r, false
+	}
+	ctx := context.WithValue(r.Context(), validatedQueryKey, v)
+	return r.WithContext(ctx), true
+}
+
+// validateBodyStep checks the JSON body of a route that used ValidateBody,
+// optionally decoding and validating it into the DTO type t. The body is restored so handlers can read it again.
+func validateBodyStep(w http.ResponseWriter, r *http.Request, t reflect.Type) (*http.Request, bool) {
+	// read entire body and restore later so handler can read it too
+	b, ok := readBody(w, r)
+	if !ok {
+		return r, false
+	}
+	// the Content-Type selects the codec; requests without one are JSON
+	c, ok := requestCodec(r)
+	if !ok {
+		writeUnsupportedMediaType(w, r, supportedMediaTypes())
+		return r, false
+	}
+	// syntax check; an empty body is invalid for endpoints expecting one
+	var tmp interface{}
+	if len(b) == 0 || c.Unmarshal(b, &tmp) != nil {
+		writeInvalidBody(w, r)
+		return r, false
+	}
+
+	// strict mode rejects unknown/duplicate fields and trailing values
+	if isJSONCodec(c) && isStrict(r) {
+		fields, err := checkStrictJSON(b, t)
+		if err != nil {
+			writeInvalidBody(w, r)
+			return r, false
+		}
+		if len(fields) > 0 {
+			writeValidationFailed(w, r, fields)
+			return r, false
+		}
+	}
+
+	// if a concrete DTO type was provided, unmarshal into it and run validation
+	if t != nil {
+		v := newDTO(t)
+		if err := c.Unmarshal(b, v); err != nil {
+			writeInvalidBody(w, r)
+			return r, false
+		}
+		if err := Validate(v); err != nil {
+			writeValidationFailed(w, r, FormatValidationErrors(err, v))
+			return r, false
+		}
+		// store validated value in request context for handler use
+		ctx := context.WithValue(r.Context(), validatedBodyKey, v)
+		r = r.WithContext(ctx)
+	}
+
+	// restore body for downstream handlers
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	return r, true
+}
+
+// readBody reads the whole request body. Bodies over the route limit get a
+// 413 envelope; other read failures a plain 500.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			writePayloadTooLarge(w, r, mbe.Limit)
+		} else {
+			http.Error(w, "Failed to read body", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return b, true
+}
+
+// writeInvalidBody writes the standard 400 envelope for malformed bodies.
+func writeInvalidBody(w http.ResponseWriter, r *http.Request) {
+	msg, ok := Message(r, "locale.invalid_body")
+	if !ok {
+		msg = "Invalid request body"
+	}
+	WriteError(w, r, http.StatusBadRequest, msg, nil, ErrorExtras{
+		Code: "INVALID_REQUEST",
+		Type: "INVALID_BODY",
+	})
+}
+
+// writeInvalidParams writes the standard 400 envelope for path parameters
+// that could not be converted to their declared type.
+func writeInvalidParams(w http.ResponseWriter, r *http.Request, fields []FieldError) {
+	localizeFieldErrors(r, fields)
+	msg, ok := Message(r, "locale.invalid_params")
+	if !ok || msg == "" {
+		msg = "Invalid path parameters"
+	}
+	WriteError(w, r, http.StatusBadRequest, msg, fields, ErrorExtras{
+		Code: "INVALID_REQUEST",
+		Type: "INVALID_PARAMS",
+	})
+}
+
+// writeValidationFailed localizes fields and writes the standard 422
+// validation envelope.
+func writeValidationFailed(w http.ResponseWriter, r *http.Request, fields []FieldError) {
+	localizeFieldErrors(r, fields)
+
+	msg, ok := Message(r, "locale.validation_failed")
+	if !ok || msg == "" {
+		msg = "Validation failed"
+	}
+	WriteError(w, r, http.StatusUnprocessableEntity, msg, fields, ErrorExtras{
+		Code: "VALIDATION_ERROR",
+		Type: "INVALID_ATTRIBUTES",
+	})
+}
+
+// localizeFieldErrors rewrites each field message using resources with
+// precedence:
+//  1. fields.<field>.<rule>
+//  2. rules.<rule>
+//  3. fields.<field>
+func localizeFieldErrors(r *http.Request, fields []FieldError) {
+	for i := range fields {
+		fe := &fields[i]
+		// parse rule and param from Code. supported formats:
+		// "INVALID_<RULE>|<param>", "invalid_<Rule>", "RULE", "RULE|param"
+		code := fe.Code
+		param := ""
+		parts := strings.SplitN(code, "|", 2)
+		main := parts[0]
+		if len(parts) > 1 {
+			param = parts[1]
+		}
+		// strip optional "INVALID_" prefix (case-insensitive)
+		if strings.HasPrefix(strings.ToUpper(main), "INVALID_") {
+			main = main[len("INVALID_"):]
+		}
+		rule := strings.ToLower(main)
+
+		var msgStr string
+		keysToTry := []string{
+			fmt.Sprintf("fields.%s.%s", strings.ToLower(fe.Field), rule),
+			fmt.Sprintf("rules.%s", rule),
+			fmt.Sprintf("fields.%s", strings.ToLower(fe.Field)),
+		}
+		for _, k := range keysToTry {
+			if s, ok := Message(r, k); ok && s != "" {
+				msgStr = s
+				break
+			}
+		}
+		if msgStr == "" {
+			if fe.Message != "" {
+				msgStr = fe.Message
+			} else {
+				msgStr = "Invalid value"
+			}
+		}
+		fieldLabel := fe.Field
+		if s, ok := Message(r, fmt.Sprintf("fields.%s", strings.ToLower(fe.Field))); ok && s != "" {
+			fieldLabel = s
+		}
+		msgStr = strings.ReplaceAll(msgStr, "{field}", fieldLabel)
+		msgStr = strings.ReplaceAll(msgStr, "{param}", param)
+		fe.Message = msgStr
+	}
 }
 
 // Get registers a GET handler under the group's prefix.
 func (g *Group) Get(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
-	full := join(g.prefix, p)
-	return registerAndChain(g.parent.With(mws...), "GET", full, h)
+	return g.Match([]string{http.MethodGet}, p, h, mws...)
+}
+
+// Head registers a HEAD handler under the group's prefix.
+func (g *Group) Head(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return g.Match([]string{http.MethodHead}, p, h, mws...)
 }
 
 // Post registers a POST handler under the group's prefix.
 func (g *Group) Post(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
-	full := join(g.prefix, p)
-	return registerAndChain(g.parent.With(mws...), "POST", full, h)
+	return g.Match([]string{http.MethodPost}, p, h, mws...)
+}
+
+// Put registers a PUT handler under the group's prefix.
+func (g *Group) Put(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return g.Match([]string{http.MethodPut}, p, h, mws...)
 }
 
 // Patch registers a PATCH handler under the group's prefix.
 func (g *Group) Patch(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
-	full := join(g.prefix, p)
-	return registerAndChain(g.parent.With(mws...), "PATCH", full, h)
+	return g.Match([]string{http.MethodPatch}, p, h, mws...)
 }
 
 // Delete registers a DELETE handler under the group's prefix.
 func (g *Group) Delete(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return g.Match([]string{http.MethodDelete}, p, h, mws...)
+}
+
+// Options registers an OPTIONS handler under the group's prefix. Without an
+// explicit OPTIONS route the router answers with 204 and an Allow header.
+func (g *Group) Options(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return g.Match([]string{http.MethodOptions}, p, h, mws...)
+}
+
+// Any registers the handler for every method in AnyMethods.
+func (g *Group) Any(p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
+	return g.Match(AnyMethods, p, h, mws...)
+}
+
+// Match registers the handler for each of the provided methods. The
+// returned chain configures all of them at once.
+func (g *Group) Match(methods []string, p string, h interface{}, mws ...func(http.Handler) http.Handler) *RouteChain {
 	full := join(g.prefix, p)
-	return registerAndChain(g.parent.With(mws...), "DELETE", full, h)
+	parent := g.parent.With(mws...)
+	all := append(append([]func(http.Handler) http.Handler(nil), g.mws...), mws...)
+	rc := &RouteChain{table: g.router.table, namePrefix: g.namePrefix}
+	for _, m := range methods {
+		rc.keys = append(rc.keys, g.router.register(parent, g.host, m, full, h, all))
+	}
+	rc.update(func(r *route) {
+		r.params = g.params
+		r.timeout = g.timeout
+		r.version = g.version
+		r.deprecated = g.deprecated
+		r.sunset = g.sunset
+	})
+	return rc
 }
 
 // BodyAs retrieves a previously-validated request body (set by ValidateBody)
 // and attempts to return it as type T. The returned bool is false when the
 // validated body is not present or cannot be asserted to T.
 func BodyAs[T any](r *http.Request) (T, bool) {
+	return contextValueAs[T](r, validatedBodyKey)
+}
+
+// QueryAs retrieves a previously-validated query DTO (set by ValidateQuery)
+// and attempts to return it as type T. It behaves like BodyAs.
+func QueryAs[T any](r *http.Request) (T, bool) {
+	return contextValueAs[T](r, validatedQueryKey)
+}
+
+// ParamsAs retrieves a previously-validated path params DTO (set by
+// ValidateParams) and attempts to return it as type T.
+func ParamsAs[T any](r *http.Request) (T, bool) {
+	return contextValueAs[T](r, validatedParamsKey)
+}
+
+// contextValueAs reads key from the request context and asserts it to T,
+// dereferencing pointers when T is the value type.
+func contextValueAs[T any](r *http.Request, key ctxKey) (T, bool) {
 	var zero T
 	if r == nil {
 		return zero, false
 	}
-	v := r.Context().Value(validatedBodyKey)
+	v := r.Context().Value(key)
 	if v == nil {
 		return zero, false
 	}
@@ -493,38 +1053,44 @@ func Message(r *http.Request, key string) (string, bool) {
 	return zero, false
 }
 
-// URLFor builds a path for a named route using the provided params map.
-// Parameters replace placeholders like `{id}` or `{id:regex}` in the route
-// template. Returns the built path and true on success, empty string and
-// false when the name was not found or required params are missing.
+// URLFor builds a path for a named route registered on the default router
+// (see SetDefaultRouter) using the provided params map. Parameters replace
+// placeholders like `{id}` or `{id:regex}` in the route template. Returns
+// the built path and true on success, empty string and false when the name
+// was not found or no default router is set.
 func URLFor(name string, params map[string]string) (string, bool) {
-	if name == "" {
+	return DefaultRouter().URLFor(name, params)
+}
+
+// URLFor builds a path for a named route registered on this router or on
+// a router mounted under it. It behaves like the package-level URLFor,
+// except that routes declared through Host yield absolute URLs whose host
+// placeholders are filled from params too (see URLScheme).
+func (rt *Router) URLFor(name string, params map[string]string) (string, bool) {
+	if rt == nil || name == "" {
 		return "", false
 	}
-	nameMu.RLock()
-	key, ok := nameToKey[name]
-	if !ok {
-		nameMu.RUnlock()
-		return "", false
+	if tpl, host, ok := rt.table.pathFor(name); ok {
+		u := rt.prefix() + buildPath(tpl, params)
+		if host != "" {
+			u = rt.scheme() + "://" + buildPath(host, params) + u
+		}
+		return u, true
 	}
-	tpl, ok2 := keyToPath[key]
-	nameMu.RUnlock()
-	if !ok2 {
-		return "", false
+	for _, sub := range rt.mounts {
+		if u, ok := sub.URLFor(name, params); ok {
+			return u, true
+		}
 	}
+	return "", false
+}
 
-	// replace placeholders {name} or {name:regex} with params[name]
-	re := regexp.MustCompile(`\{([a-zA-Z0-9_]+)(:[^}]+)?\}`)
-	out := re.ReplaceAllStringFunc(tpl, func(m string) string {
-		parts := re.FindStringSubmatch(m)
-		if len(parts) >= 2 {
-			k := parts[1]
-			if v, ok := params[k]; ok {
-				return v
-			}
-		}
-		// missing param -> empty string
-		return ""
-	})
-	return out, true
+// RouterFrom returns the Router that matched the request, or nil when the
+// request was not dispatched through a Router route.
+func RouterFrom(r *http.Request) *Router {
+	if r == nil {
+		return nil
+	}
+	rt, _ := r.Context().Value(routerKey).(*Router)
+	return rt
 }
@@ -0,0 +1,90 @@
+package kyugo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterHandlerNameTargetsDefaultRouterOnly(t *testing.T) {
+	t.Parallel()
+	d := &defaultRouting{}
+	d.register(http.MethodGet, "/global", "missing:global")
+
+	main, other := NewRouter(), NewRouter()
+	main.Mount("/api", NewRouter())
+	if n := len(main.Routes()); n != 0 {
+		t.Fatalf("routes installed before a default router was set: %+v", main.Routes())
+	}
+
+	d.set(main, false)
+	routes := main.Routes()
+	if len(routes) != 1 || routes[0].Path != "/global" {
+		t.Fatalf("default router routes = %+v, want only GET /global", routes)
+	}
+	if n := len(other.Routes()); n != 0 {
+		t.Fatalf("other router got %d global routes", n)
+	}
+
+	d.register(http.MethodGet, "/later", "missing:later")
+	if n := len(main.Routes()); n != 2 {
+		t.Fatalf("route registered after SetDefaultRouter not installed: %+v", main.Routes())
+	}
+
+	w := httptest.NewRecorder()
+	other.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/global", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("other router served a global route: %d", w.Code)
+	}
+}
+
+func TestLaterServersKeepTheDefaultRouter(t *testing.T) {
+	t.Parallel()
+	d := &defaultRouting{}
+	admin, public := NewRouter(), NewRouter()
+
+	if !d.set(admin, true) {
+		t.Fatal("first router did not become the default")
+	}
+	if d.set(public, true) || d.router() != admin {
+		t.Fatal("a later server replaced the default router")
+	}
+	d.set(public, false)
+	if d.router() != public {
+		t.Fatal("SetDefaultRouter did not replace the default router")
+	}
+}
+
+func TestRouteTableIsPerRouter(t *testing.T) {
+	t.Parallel()
+	okHandler := func(w http.ResponseWriter, r *http.Request) {}
+
+	a, b := NewRouter(), NewRouter()
+	a.Get("/users/{id}", okHandler).Name("users.show")
+	b.Get("/users/{id}", okHandler).Name("users.show").MaxBodySize(1024)
+
+	api := NewRouter()
+	api.Post("/orders/{id:[0-9]+}", okHandler).Name("orders.update")
+	a.Mount("/api", api)
+
+	if u, ok := a.URLFor("users.show", map[string]string{"id": "7"}); !ok || u != "/users/7" {
+		t.Fatalf("a.URLFor = %q, %v", u, ok)
+	}
+	if u, ok := a.URLFor("orders.update", map[string]string{"id": "9"}); !ok || u != "/api/orders/9" {
+		t.Fatalf("mounted URLFor = %q, %v", u, ok)
+	}
+	if _, ok := b.URLFor("orders.update", nil); ok {
+		t.Fatal("b resolved a route of a's mounted router")
+	}
+
+	routes := a.Routes()
+	if len(routes) != 2 || routes[0].Path != "/users/{id}" || routes[1].Path != "/api/orders/{id:[0-9]+}" || routes[1].Method != http.MethodPost {
+		t.Fatalf("a.Routes() = %+v", routes)
+	}
+	if routes[0].MaxBody == 1024 {
+		t.Fatal("b's route options leaked into a's table")
+	}
+	if got := b.Routes(); len(got) != 1 || got[0].MaxBody != 1024 || got[0].Name != "users.show" {
+		t.Fatalf("b.Routes() = %+v", got)
+	}
+}
@@ -0,0 +1,253 @@
+package kyugo
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// routeParamRe matches `{name}` and `{name:regex}` placeholders.
+var routeParamRe = regexp.MustCompile(`\{([a-zA-Z0-9_]+)(:[^}]+)?\}`)
+
+// route holds the metadata recorded for a single METHOD + path registration.
+type route struct {
+	method string
+	path   string // path template as registered with chi, regexes included
+	host   string // host pattern for routes declared through Router.Host
+	name   string
+	// handler is the resolved function name of the registered handler.
+	handler string
+	// groupMiddleware names middleware applied through groups (Use/With)
+	// or passed at registration; those run inside chi and are not stored.
+	groupMiddleware []string
+	// hasBody is true when ValidateBody was called; body may still be nil
+	// in which case only JSON syntax is checked.
+	hasBody    bool
+	body       reflect.Type
+	query      reflect.Type
+	params     reflect.Type
+	form       reflect.Type
+	middleware []func(http.Handler) http.Handler
+	// maxBodySize limits the request body; zero falls back to the router
+	// limits and a negative value disables them.
+	maxBodySize int64
+	strict      bool
+	// req and res are the types of handlers created with Handle.
+	req     reflect.Type
+	res     reflect.Type
+	timeout time.Duration
+	// version is the API version declared through a version group;
+	// deprecated routes advertise Deprecation and Sunset headers.
+	version    string
+	deprecated bool
+	sunset     time.Time
+	// sparse is set by SparseFields; fields is the response type the
+	// selection is checked against, when declared.
+	sparse bool
+	fields reflect.Type
+}
+
+// routeTable is the per-Router store of route metadata. Routes are keyed by
+// "METHOD path" and keep their registration order.
+type routeTable struct {
+	mu     sync.RWMutex
+	routes map[string]*route
+	order  []string
+	names  map[string]string // name -> key
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		routes: make(map[string]*route),
+		names:  make(map[string]string),
+	}
+}
+
+func routeKey(method, p string) string {
+	return strings.ToUpper(method) + " " + p
+}
+
+// add records rt and returns its key. Registering the same method and path
+// again resets its metadata, matching chi's last-wins behaviour.
+func (t *routeTable) add(rt *route) string {
+	rt.method = strings.ToUpper(rt.method)
+	key := routeKey(rt.method, rt.path)
+	if rt.host != "" {
+		key = rt.host + " " + key
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.routes[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.routes[key] = rt
+	return key
+}
+
+// update applies fn to each route identified by keys.
+func (t *routeTable) update(keys []string, fn func(*route)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range keys {
+		if rt, ok := t.routes[k]; ok {
+			fn(rt)
+		}
+	}
+}
+
+// get returns a copy of the route stored under key.
+func (t *routeTable) get(key string) (route, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rt, ok := t.routes[key]
+	if !ok {
+		return route{}, false
+	}
+	return *rt, true
+}
+
+// setName associates name with key for reverse lookups.
+func (t *routeTable) setName(name, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.names[name] = key
+	if rt, ok := t.routes[key]; ok {
+		rt.name = name
+	}
+}
+
+// pathFor returns the path template and host pattern of the route
+// registered under name.
+func (t *routeTable) pathFor(name string) (string, string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	key, ok := t.names[name]
+	if !ok {
+		return "", "", false
+	}
+	rt, ok := t.routes[key]
+	if !ok {
+		return "", "", false
+	}
+	return rt.path, rt.host, true
+}
+
+// buildPath replaces placeholders {name} or {name:regex} in tpl with
+// params[name]. Missing params are replaced with an empty string.
+func buildPath(tpl string, params map[string]string) string {
+	return routeParamRe.ReplaceAllStringFunc(tpl, func(m string) string {
+		parts := routeParamRe.FindStringSubmatch(m)
+		if len(parts) >= 2 {
+			if v, ok := params[parts[1]]; ok {
+				return v
+			}
+		}
+		return ""
+	})
+}
+
+// RouteInfo describes a registered route as returned by Router.Routes.
+type RouteInfo struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Host       string   `json:"host,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Handler    string   `json:"handler"`
+	Body       string   `json:"body,omitempty"`
+	Query      string   `json:"query,omitempty"`
+	Params     string   `json:"params,omitempty"`
+	Form       string   `json:"form,omitempty"`
+	Request    string   `json:"request,omitempty"`
+	Response   string   `json:"response,omitempty"`
+	Timeout    string   `json:"timeout,omitempty"`
+	MaxBody    int64    `json:"max_body,omitempty"`
+	Strict     bool     `json:"strict,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Deprecated bool     `json:"deprecated,omitempty"`
+	Sunset     string   `json:"sunset,omitempty"`
+	Sparse     bool     `json:"sparse_fields,omitempty"`
+	Middleware []string `json:"middleware,omitempty"`
+}
+
+// RoutesDebugPath is where NewServer exposes the route listing when the
+// application runs with `app.debug` enabled.
+const RoutesDebugPath = "/__kyugo/routes"
+
+// list returns RouteInfo values for every route in registration order,
+// with paths prefixed by prefix.
+func (t *routeTable) list(prefix string) []RouteInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	out := make([]RouteInfo, 0, len(t.order))
+	for _, key := range t.order {
+		rt := t.routes[key]
+		info := RouteInfo{
+			Method:   rt.method,
+			Path:     prefix + rt.path,
+			Host:     rt.host,
+			Name:     rt.name,
+			Handler:  rt.handler,
+			Body:     typeName(rt.body),
+			Query:    typeName(rt.query),
+			Params:   typeName(rt.params),
+			Form:     typeName(rt.form),
+			Request:  typeName(rt.req),
+			Response: typeName(rt.res),
+		}
+		if rt.timeout > 0 {
+			info.Timeout = rt.timeout.String()
+		}
+		info.MaxBody = rt.maxBodySize
+		info.Strict = rt.strict
+		info.Version = rt.version
+		info.Deprecated = rt.deprecated
+		info.Sparse = rt.sparse
+		if !rt.sunset.IsZero() {
+			info.Sunset = rt.sunset.UTC().Format(time.RFC3339)
+		}
+		info.Middleware = append(info.Middleware, rt.groupMiddleware...)
+		for _, mw := range rt.middleware {
+			info.Middleware = append(info.Middleware, funcName(mw))
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// funcName resolves the fully qualified name of the function f points to.
+// Method values are reported without the compiler's "-fm" suffix; values
+// that are not functions are reported by type.
+func funcName(f interface{}) string {
+	if f == nil {
+		return ""
+	}
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func {
+		return v.Type().String()
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return v.Type().String()
+	}
+	return strings.TrimSuffix(fn.Name(), "-fm")
+}
+
+// funcNames maps funcName over mws.
+func funcNames(mws []func(http.Handler) http.Handler) []string {
+	out := make([]string, 0, len(mws))
+	for _, mw := range mws {
+		out = append(out, funcName(mw))
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+package kyugo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-kyugo/kyugo/registry"
+)
+
+// RoutesFile is the document read by Router.LoadRoutes.
+//
+//	routes:
+//	  - method: POST
+//	    path: /products
+//	    handler: products.create
+//	    name: products.create
+//	    body: dto.CreateProduct
+//	    middleware: [auth]
+//	    timeout: 5s
+type RoutesFile struct {
+	Routes []RouteSpec `json:"routes" yaml:"routes"`
+}
+
+// RouteSpec declares one route. Handler, DTO and middleware names are
+// resolved with registry.Handler, registry.DTO and registry.Middleware.
+type RouteSpec struct {
+	// Method is a single HTTP method or "ANY"; Methods lists several.
+	Method     string   `json:"method,omitempty" yaml:"method,omitempty"`
+	Methods    []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	Path       string   `json:"path" yaml:"path"`
+	Handler    string   `json:"handler" yaml:"handler"`
+	Name       string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Body       string   `json:"body,omitempty" yaml:"body,omitempty"`
+	Query      string   `json:"query,omitempty" yaml:"query,omitempty"`
+	Params     string   `json:"params,omitempty" yaml:"params,omitempty"`
+	Form       string   `json:"form,omitempty" yaml:"form,omitempty"`
+	Middleware []string `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+	// Timeout is a time.ParseDuration string such as "5s".
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// knownMethods are the methods a RouteSpec may name.
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodConnect: true, http.MethodTrace: true,
+}
+
+// resolvedSpec is a RouteSpec whose names were all found.
+type resolvedSpec struct {
+	spec    RouteSpec
+	methods []string
+	handler interface{}
+	body    interface{}
+	query   interface{}
+	params  interface{}
+	form    interface{}
+	mws     []func(http.Handler) http.Handler
+	timeout time.Duration
+}
+
+// LoadRoutes registers the routes declared in the JSON (.json) or YAML
+// (.yaml, .yml) file p. The file is looked up among the loaded resources
+// first (see LoadResources) and then on disk. Every handler, DTO and
+// middleware name is resolved before any route is added; when one is
+// missing, or a method or timeout is invalid, LoadRoutes registers nothing
+// and returns an error listing every problem. Unknown keys are rejected
+// too so typos surface at startup.
+func (rt *Router) LoadRoutes(p string) error {
+	b, ok := GetResource(p)
+	if !ok {
+		var err error
+		if b, err = os.ReadFile(p); err != nil {
+			return fmt.Errorf("kyugo: routes file %s: %w", p, err)
+		}
+	}
+
+	var file RoutesFile
+	var err error
+	switch strings.ToLower(path.Ext(p)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(&file)
+	default:
+		return fmt.Errorf("kyugo: routes file %s: unsupported extension", p)
+	}
+	if err != nil {
+		return fmt.Errorf("kyugo: routes file %s: %w", p, err)
+	}
+
+	specs, err := resolveRouteSpecs(file.Routes)
+	if err != nil {
+		return fmt.Errorf("kyugo: routes file %s: %w", p, err)
+	}
+	for _, rs := range specs {
+		rc := rt.Match(rs.methods, rs.spec.Path, rs.handler)
+		if len(rs.mws) > 0 {
+			rc.Middleware(rs.mws...)
+		}
+		if rs.body != nil {
+			rc.ValidateBody(rs.body)
+		}
+		if rs.query != nil {
+			rc.ValidateQuery(rs.query)
+		}
+		if rs.params != nil {
+			rc.ValidateParams(rs.params)
+		}
+		if rs.form != nil {
+			rc.ValidateForm(rs.form)
+		}
+		if rs.timeout > 0 {
+			rc.Timeout(rs.timeout)
+		}
+		if rs.spec.Name != "" {
+			rc.Name(rs.spec.Name)
+		}
+	}
+	return nil
+}
+
+// resolveRouteSpecs looks up every name used by specs, collecting all
+// failures.
+func resolveRouteSpecs(specs []RouteSpec) ([]resolvedSpec, error) {
+	var errs []error
+	out := make([]resolvedSpec, 0, len(specs))
+	for i, spec := range specs {
+		rs := resolvedSpec{spec: spec}
+		var names []string
+		for _, m := range append([]string{spec.Method}, spec.Methods...) {
+			if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+				names = append(names, m)
+			}
+		}
+		where := fmt.Sprintf("route %d (%s %s)", i+1, strings.Join(names, ","), spec.Path)
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]interface{}{where}, args...)...))
+		}
+
+		if !strings.HasPrefix(spec.Path, "/") {
+			fail("path must start with /")
+		}
+		for _, m := range names {
+			switch {
+			case m == "ANY":
+				rs.methods = append(rs.methods, AnyMethods...)
+			case knownMethods[m]:
+				rs.methods = append(rs.methods, m)
+			default:
+				fail("unknown method %q", m)
+			}
+		}
+		if len(names) == 0 {
+			fail("no method")
+		}
+
+		if h, ok := registry.Handler(spec.Handler); !ok {
+			fail("unknown handler %q", spec.Handler)
+		} else if _, err := handlerToHTTP(h); err != nil {
+			fail("handler %q: %v", spec.Handler, err)
+		} else {
+			rs.handler = h
+		}
+
+		dto := func(kind, name string) interface{} {
+			if name == "" {
+				return nil
+			}
+			v, ok := registry.DTO(name)
+			if !ok || v == nil {
+				fail("unknown %s DTO %q", kind, name)
+				return nil
+			}
+			return v
+		}
+		rs.body = dto("body", spec.Body)
+		rs.query = dto("query", spec.Query)
+		rs.params = dto("params", spec.Params)
+		rs.form = dto("form", spec.Form)
+
+		for _, name := range spec.Middleware {
+			mw, ok := registry.Middleware(name)
+			if !ok {
+				fail("unknown middleware %q", name)
+				continue
+			}
+			rs.mws = append(rs.mws, mw)
+		}
+
+		if spec.Timeout != "" {
+			d, err := time.ParseDuration(spec.Timeout)
+			if err != nil || d < 0 {
+				fail("invalid timeout %q", spec.Timeout)
+			}
+			rs.timeout = d
+		}
+		out = append(out, rs)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
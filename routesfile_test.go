@@ -0,0 +1,153 @@
+package kyugo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-kyugo/kyugo/registry"
+)
+
+type routesFileProduct struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func init() {
+	registry.RegisterHandler("routesfile_test.create", func(resp *Response, req *Request) {
+		resp.JSON(http.StatusCreated, "", nil)
+	})
+	registry.RegisterHandler("routesfile_test.list", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(w.Header().Get("X-Tagged")))
+	})
+	registry.RegisterDTO("routesfile_test.product", &routesFileProduct{})
+	registry.RegisterMiddleware("routesfile_test.tag", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Tagged", "yes")
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+func writeRoutesFile(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadRoutesRejectsUnresolvedNames(t *testing.T) {
+	valid := RouteSpec{Method: "GET", Path: "/ok", Handler: "routesfile_test.list"}
+	for _, tc := range []struct {
+		name  string
+		specs []RouteSpec
+		want  []string
+	}{
+		{
+			name:  "unknown handler",
+			specs: []RouteSpec{valid, {Method: "GET", Path: "/x", Handler: "missing.handler"}},
+			want:  []string{`route 2 (GET /x): unknown handler "missing.handler"`},
+		},
+		{
+			name:  "unknown middleware",
+			specs: []RouteSpec{{Method: "GET", Path: "/x", Handler: "routesfile_test.list", Middleware: []string{"routesfile_test.tag", "missing.mw"}}},
+			want:  []string{`unknown middleware "missing.mw"`},
+		},
+		{
+			name:  "unknown DTO",
+			specs: []RouteSpec{{Method: "POST", Path: "/x", Handler: "routesfile_test.create", Body: "missing.dto", Query: "missing.query"}},
+			want:  []string{`unknown body DTO "missing.dto"`, `unknown query DTO "missing.query"`},
+		},
+		{
+			name:  "bad method",
+			specs: []RouteSpec{{Methods: []string{"get", "FETCH"}, Path: "/x", Handler: "routesfile_test.list"}},
+			want:  []string{`route 1 (GET,FETCH /x): unknown method "FETCH"`},
+		},
+		{
+			name:  "every problem",
+			specs: []RouteSpec{{Path: "x", Handler: "missing.handler", Timeout: "soon"}},
+			want:  []string{"path must start with /", "no method", `unknown handler "missing.handler"`, `invalid timeout "soon"`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(RoutesFile{Routes: tc.specs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rt := NewRouter()
+			err = rt.LoadRoutes(writeRoutesFile(t, "routes.json", string(b)))
+			if err == nil {
+				t.Fatal("LoadRoutes succeeded")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+			if routes := rt.Routes(); len(routes) != 0 {
+				t.Errorf("registered %d routes despite the error", len(routes))
+			}
+		})
+	}
+}
+
+func TestLoadRoutesRejectsUnknownKeys(t *testing.T) {
+	for name, content := range map[string]string{
+		"routes.json": `{"routes":[{"method":"GET","path":"/x","handler":"routesfile_test.list","midleware":["a"]}]}`,
+		"routes.yaml": "routes:\n  - method: GET\n    path: /x\n    handler: routesfile_test.list\n    midleware: [a]\n",
+	} {
+		if err := NewRouter().LoadRoutes(writeRoutesFile(t, name, content)); err == nil || !strings.Contains(err.Error(), "midleware") {
+			t.Errorf("%s: error = %v", name, err)
+		}
+	}
+}
+
+func TestLoadRoutesJSONAndYAMLAgree(t *testing.T) {
+	file := RoutesFile{Routes: []RouteSpec{
+		{Method: "POST", Path: "/products", Handler: "routesfile_test.create", Name: "products.create", Body: "routesfile_test.product", Timeout: "5s"},
+		{Methods: []string{"GET", "HEAD"}, Path: "/products", Handler: "routesfile_test.list", Middleware: []string{"routesfile_test.tag"}},
+	}}
+	jb, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yb, err := yaml.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromJSON, fromYAML := NewRouter(), NewRouter()
+	if err := fromJSON.LoadRoutes(writeRoutesFile(t, "routes.json", string(jb))); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromYAML.LoadRoutes(writeRoutesFile(t, "routes.yml", string(yb))); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fromYAML.Routes(), fromJSON.Routes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("YAML routes = %+v\nJSON routes = %+v", got, want)
+	}
+	if routes := fromJSON.Routes(); len(routes) != 3 || routes[0].Name != "products.create" || routes[0].Timeout != "5s" {
+		t.Fatalf("routes = %+v", routes)
+	}
+
+	for _, rt := range []*Router{fromJSON, fromYAML} {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+		if w.Body.String() != "yes" {
+			t.Fatalf("GET /products = %q, want the middleware header echoed", w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{}`)))
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("POST /products without name: %d, want 422", w.Code)
+		}
+	}
+}
@@ -26,6 +26,11 @@ type Options struct {
 	DefaultMiddlewares []func(http.Handler) http.Handler
 	ReadTimeout        time.Duration
 	WriteTimeout       time.Duration
+	// RoutesFile optionally names a JSON or YAML routes file (for example
+	// "routes.yaml" under resources/) loaded into the default router with
+	// Router.LoadRoutes. Register the handlers, DTOs and middleware it
+	// names in the registry package before calling NewServer.
+	RoutesFile string
 }
 
 // LoggerConfig represents structured logger configuration passed to the server.
@@ -101,16 +106,7 @@ func NewServer(opts Options) (*Server, error) {
 		base = rt.Handler()
 	}
 
-	// if messages were loaded, wrap base to inject them into the request context
-	if msgs != nil {
-		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := context.WithValue(r.Context(), MessagesKey, msgs)
-			base.ServeHTTP(w, r.WithContext(ctx))
-		})
-	} else {
-		h = base
-	}
-
+	h = base
 	if len(opts.DefaultMiddlewares) > 0 {
 		for i := len(opts.DefaultMiddlewares) - 1; i >= 0; i-- {
 			mw := opts.DefaultMiddlewares[i]
@@ -118,6 +114,16 @@ func NewServer(opts Options) (*Server, error) {
 		}
 	}
 
+	// if messages were loaded, inject them into the request context before
+	// any middleware runs so middleware responses can be localized too
+	if msgs != nil {
+		inner := h
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), MessagesKey, msgs)
+			inner.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
 	srv := &http.Server{
 		Addr:         addr,
 		Handler:      h,
@@ -129,6 +135,26 @@ func NewServer(opts Options) (*Server, error) {
 	s.router = rt
 	if rt != nil {
 		rt.server = s
+		// keep the default chosen by an earlier server or SetDefaultRouter
+		defaults.set(rt, true)
+		if cfgSrc != nil {
+			rt.MaxUploadSize(cfgSrc.Server.MaxUploadSizeBytes)
+			rt.MaxBodySize(cfgSrc.Server.MaxBodySizeBytes)
+			rt.StrictJSON(cfgSrc.Server.StrictJSON)
+			rt.CorsConfig(cfgSrc.Server.Cors)
+			if err := checkAppSecret(cfgSrc.App.Secret); err != nil {
+				return nil, err
+			}
+			rt.CursorKey([]byte(cfgSrc.App.Secret))
+		}
+		if cfgSrc != nil && cfgSrc.App.Debug {
+			rt.Get(RoutesDebugPath, rt.RoutesHandler()).Name("kyugo.routes")
+		}
+		if opts.RoutesFile != "" {
+			if err := rt.LoadRoutes(opts.RoutesFile); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// connect database if present in config
@@ -0,0 +1,516 @@
+package kyugo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// SparseFields lets clients of the previously registered route select the
+// fields of the response data:
+//
+//	GET /products?fields=id,name,price
+//	GET /orders?fields=id,items.sku,customer.name
+//	GET /orders?fields[product]=id,name
+//
+// `fields` applies to the data itself (to each element of slices) and dot
+// paths select nested fields. `fields[type]` applies to every object of
+// that type anywhere in the data, types being named by their snake_cased
+// Go name (OrderItem is `order_item`). Fields are named by their json tags.
+//
+// SuccessResponse, Response.JSON, Paginated and typed handlers then encode
+// only the selected fields, with every negotiated codec. Fields the data
+// does not have are rejected with the standard 422 validation envelope.
+// When the response type is known — passed as dto, or the response type of
+// a Handle handler — that happens before the handler runs. Otherwise the
+// selection can only be checked against the data when the response is
+// written: the handler has already done its work and the router's error
+// handler then writes the 422 in place of the success envelope. Declare the
+// type for handlers with side effects.
+func (rc *RouteChain) SparseFields(dto ...interface{}) *RouteChain {
+	var t reflect.Type
+	if len(dto) > 0 && dto[0] != nil {
+		t = reflect.TypeOf(dto[0])
+	}
+	return rc.update(func(r *route) {
+		r.sparse = true
+		if t != nil {
+			r.fields = t
+		}
+	})
+}
+
+// fieldSet maps JSON names to the selection below them; a nil value keeps
+// the whole field.
+type fieldSet map[string]fieldSet
+
+func (s fieldSet) add(path []string) {
+	child, ok := s[path[0]]
+	if len(path) == 1 {
+		s[path[0]] = nil
+		return
+	}
+	if ok && child == nil {
+		// the whole field is already selected
+		return
+	}
+	if child == nil {
+		child = fieldSet{}
+		s[path[0]] = child
+	}
+	child.add(path[1:])
+}
+
+// fieldSelection is a parsed fields query.
+type fieldSelection struct {
+	root  fieldSet
+	types map[string]fieldSet
+}
+
+// parseFieldSelection reads the fields parameters of q. It returns nil
+// when none were sent.
+func parseFieldSelection(q url.Values) (*fieldSelection, []FieldError) {
+	sel := &fieldSelection{types: map[string]fieldSet{}}
+	var errs []FieldError
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		if k == "fields" || strings.HasPrefix(k, "fields[") {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		set := fieldSet{}
+		var bad []string
+		for _, p := range strings.Split(strings.Join(q[key], ","), ",") {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			path := strings.Split(p, ".")
+			if key != "fields" && len(path) > 1 {
+				bad = append(bad, p)
+				continue
+			}
+			valid := true
+			for _, seg := range path {
+				valid = valid && seg != ""
+			}
+			if !valid {
+				bad = append(bad, p)
+				continue
+			}
+			set.add(path)
+		}
+		if len(bad) > 0 {
+			errs = append(errs, invalidFields(key, bad))
+			continue
+		}
+		if key == "fields" {
+			sel.root = set
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "fields["), "]")
+		if name == "" || strings.ContainsAny(name, "[]") || !strings.HasSuffix(key, "]") {
+			errs = append(errs, FieldError{Field: key, Code: "INVALID_UNKNOWN_FIELD", Message: key + " is not a valid parameter"})
+			continue
+		}
+		sel.types[name] = set
+	}
+	return sel, errs
+}
+
+func invalidFields(key string, paths []string) FieldError {
+	list := strings.Join(paths, ",")
+	return FieldError{Field: key, Code: "INVALID_FIELDS|" + list, Message: key + " selects unknown fields: " + list}
+}
+
+// check reports the selected fields that t does not have.
+func (sel *fieldSelection) check(t reflect.Type) []FieldError {
+	var errs []FieldError
+	if sel.root != nil {
+		var bad []string
+		checkFieldSet(t, sel.root, "", &bad)
+		if len(bad) > 0 {
+			errs = append(errs, invalidFields("fields", bad))
+		}
+	}
+	names := make([]string, 0, len(sel.types))
+	for name := range sel.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := "fields[" + name + "]"
+		found := findResource(t, name, map[reflect.Type]bool{})
+		if found == nil {
+			errs = append(errs, FieldError{Field: key, Code: "INVALID_UNKNOWN_FIELD", Message: name + " is not a type of this response"})
+			continue
+		}
+		var bad []string
+		checkFieldSet(found, sel.types[name], "", &bad)
+		if len(bad) > 0 {
+			errs = append(errs, invalidFields(key, bad))
+		}
+	}
+	return errs
+}
+
+func checkFieldSet(t reflect.Type, set fieldSet, prefix string, bad *[]string) {
+	t = itemType(t)
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := set[name]
+		var ft reflect.Type
+		switch {
+		case t == nil || t.Kind() == reflect.Interface:
+			// the dynamic type is checked when the response is written
+			continue
+		case isJSONOpaque(t):
+		case t.Kind() == reflect.Struct:
+			if f, ok := jsonFieldsOf(t).byName[name]; ok {
+				ft = f.typ
+			}
+		case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			ft = t.Elem()
+		}
+		if ft == nil {
+			*bad = append(*bad, prefix+name)
+			continue
+		}
+		if child != nil {
+			checkFieldSet(ft, child, prefix+name+".", bad)
+		}
+	}
+}
+
+// findResource returns the struct type named name reachable from t.
+func findResource(t reflect.Type, name string, seen map[reflect.Type]bool) reflect.Type {
+	t = itemType(t)
+	if t == nil || seen[t] || isJSONOpaque(t) {
+		return nil
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Struct:
+		if resourceName(t) == name {
+			return t
+		}
+		for _, f := range jsonFieldsOf(t).list {
+			if found := findResource(f.typ, name, seen); found != nil {
+				return found
+			}
+		}
+	case reflect.Map:
+		return findResource(t.Elem(), name, seen)
+	}
+	return nil
+}
+
+// itemType strips pointers, slices and arrays from t.
+func itemType(t reflect.Type) reflect.Type {
+	for t != nil {
+		switch {
+		case isJSONOpaque(t):
+			return t
+		case t.Kind() == reflect.Ptr:
+			t = t.Elem()
+		case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+	return nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isJSONOpaque reports whether values of t encode themselves, so their
+// fields cannot be selected.
+func isJSONOpaque(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// resourceName snake_cases the Go name of t: OrderItem -> order_item,
+// APIKey -> api_key.
+func resourceName(t reflect.Type) string {
+	rs := []rune(t.Name())
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// prunedField is one member of a prunedObject.
+type prunedField struct {
+	name  string
+	value interface{}
+}
+
+// prunedObject is a struct or map reduced to its selected fields, in
+// encoding order. The values keep their Go types so every codec encodes
+// them as it would the original data.
+type prunedObject []prunedField
+
+// MarshalJSON implements json.Marshaler.
+func (o prunedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		b, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// EncodeMsgpack implements msgpack.CustomEncoder.
+func (o prunedObject) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if err := enc.EncodeMapLen(len(o)); err != nil {
+		return err
+	}
+	for _, f := range o {
+		if err := enc.EncodeString(f.name); err != nil {
+			return err
+		}
+		if err := enc.Encode(f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalCBOR implements cbor.Marshaler.
+func (o prunedObject) MarshalCBOR() ([]byte, error) {
+	// map header: major type 5 and the member count
+	var buf []byte
+	switch n := uint64(len(o)); {
+	case n < 24:
+		buf = []byte{0xa0 | byte(n)}
+	case n <= 0xff:
+		buf = []byte{0xb8, byte(n)}
+	case n <= 0xffff:
+		buf = []byte{0xb9, byte(n >> 8), byte(n)}
+	default:
+		buf = []byte{0xba, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+	for _, f := range o {
+		for _, v := range []interface{}{f.name, f.value} {
+			b, err := cbor.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			buf = append(buf, b...)
+		}
+	}
+	return buf, nil
+}
+
+// prune returns v reduced to the selected fields: structs and maps become
+// prunedObjects, slices []interface{}, and everything else keeps its value.
+func (sel *fieldSelection) prune(v reflect.Value, set fieldSet) interface{} {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !isJSONOpaque(v.Type()) {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if (set == nil && len(sel.types) == 0) || isJSONOpaque(v.Type()) {
+		return valueOf(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if ts, ok := sel.types[resourceName(v.Type())]; ok {
+			set = ts
+		}
+		obj := prunedObject{}
+		for _, f := range jsonFieldsOf(v.Type()).list {
+			var child fieldSet
+			if set != nil {
+				var ok bool
+				if child, ok = set[f.name]; !ok {
+					continue
+				}
+			}
+			fv, err := v.FieldByIndexErr(f.index)
+			if err != nil || (f.omitEmpty && isEmptyJSONValue(fv)) {
+				continue
+			}
+			obj = append(obj, prunedField{f.name, sel.prune(fv, child)})
+		}
+		return obj
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.IsNil() {
+			return valueOf(v)
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			if _, ok := set[k.String()]; ok || set == nil {
+				keys = append(keys, k.String())
+			}
+		}
+		sort.Strings(keys)
+		obj := make(prunedObject, len(keys))
+		for i, k := range keys {
+			obj[i] = prunedField{k, sel.prune(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), set[k])}
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 || (v.Kind() == reflect.Slice && v.IsNil()) {
+			return valueOf(v)
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = sel.prune(v.Index(i), set)
+		}
+		return items
+	}
+	return valueOf(v)
+}
+
+// valueOf returns v as an interface, by address when addressable so that
+// pointer-receiver marshalers still apply.
+func valueOf(v reflect.Value) interface{} {
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	return v.Interface()
+}
+
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
+
+// sparseWriter carries the field selection of a request to the success
+// envelope writers, including the request-less SuccessResponse.
+type sparseWriter struct {
+	http.ResponseWriter
+	r   *http.Request
+	sel *fieldSelection
+	// checked is true when the selection was validated against the
+	// declared response type.
+	checked bool
+}
+
+// Hijack lets WebSocket upgrades through.
+func (w *sparseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Flush lets streaming responses through.
+func (w *sparseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError flushes the wrapped writer, reporting writers that cannot.
+func (w *sparseWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController.
+func (w *sparseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// sparseWriterOf finds the sparseWriter among the writers wrapping w.
+func sparseWriterOf(w http.ResponseWriter) *sparseWriter {
+	for w != nil {
+		switch tw := w.(type) {
+		case *sparseWriter:
+			return tw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = tw.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// sparseFieldsStep parses the fields query of routes declaring
+// SparseFields and validates it against t when known. It returns the
+// writer carrying the selection and false when an error response has
+// already been written.
+func sparseFieldsStep(w http.ResponseWriter, r *http.Request, t reflect.Type) (http.ResponseWriter, bool) {
+	sel, errs := parseFieldSelection(r.URL.Query())
+	if len(errs) == 0 && sel != nil && t != nil {
+		errs = sel.check(t)
+	}
+	if len(errs) > 0 {
+		writeValidationFailed(w, r, errs)
+		return w, false
+	}
+	if sel == nil {
+		return w, true
+	}
+	return &sparseWriter{ResponseWriter: w, r: r, sel: sel, checked: t != nil}, true
+}
+
+// prune replaces env.Data with its selected fields. It returns false when
+// an error response was written instead.
+func (w *sparseWriter) prune(env *SuccessEnvelope) bool {
+	if env.Data == nil {
+		return true
+	}
+	if !w.checked {
+		if errs := w.sel.check(reflect.TypeOf(env.Data)); len(errs) > 0 {
+			handleError(w, w.r, ErrValidationFailed.WithFields(errs...))
+			return false
+		}
+	}
+	env.Data = w.sel.prune(reflect.ValueOf(env.Data), w.sel.root)
+	return true
+}
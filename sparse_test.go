@@ -0,0 +1,158 @@
+package kyugo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type sparseTag struct {
+	Label  string `json:"label"`
+	Hidden string `json:"hidden"`
+}
+
+type sparseItem struct {
+	SKU string     `json:"sku"`
+	Qty int        `json:"qty"`
+	Tag *sparseTag `json:"tag,omitempty"`
+}
+
+type sparseOrder struct {
+	ID    int            `json:"id"`
+	Name  string         `json:"name"`
+	Items []sparseItem   `json:"items"`
+	At    time.Time      `json:"at"`
+	Extra map[string]int `json:"extra"`
+}
+
+func sparseOrders() []sparseOrder {
+	return []sparseOrder{{
+		ID:    1,
+		Name:  "a",
+		Items: []sparseItem{{"x", 1, &sparseTag{"l", "h"}}, {"y", 2, nil}},
+		At:    time.Unix(0, 0).UTC(),
+		Extra: map[string]int{"k": 1, "j": 2},
+	}}
+}
+
+func sparseRouter(calls *int) *Router {
+	rt := NewRouter()
+	rt.Get("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		SuccessResponse(w, http.StatusOK, "ok", sparseOrders())
+	}).SparseFields()
+	rt.Get("/dto", func(resp *Response, req *Request) {
+		*calls++
+		resp.JSON(http.StatusOK, "ok", sparseOrders())
+	}).SparseFields([]sparseOrder{})
+	rt.Get("/typed", Handle(func(ctx context.Context, in struct{}) ([]sparseOrder, error) {
+		*calls++
+		return sparseOrders(), nil
+	})).SparseFields()
+	return rt
+}
+
+func sparseGet(rt *Router, target, accept string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	rt.ServeHTTP(w, r)
+	return w
+}
+
+func TestSparseFieldsPrunesJSON(t *testing.T) {
+	var calls int
+	rt := sparseRouter(&calls)
+	cases := map[string]string{
+		"/untyped?fields=id,items.sku,extra.k":         `[{"id":1,"items":[{"sku":"x"},{"sku":"y"}],"extra":{"k":1}}]`,
+		"/untyped?fields=name,items.tag.label":         `[{"name":"a","items":[{"tag":{"label":"l"}},{}]}]`,
+		"/dto?fields[sparse_item]=qty&fields=items,id": `[{"id":1,"items":[{"qty":1},{"qty":2}]}]`,
+		"/typed?fields=at":                             `[{"at":"1970-01-01T00:00:00Z"}]`,
+	}
+	for target, want := range cases {
+		w := sparseGet(rt, target, "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"data":`+want) {
+			t.Errorf("%s: %d %s\nwant data %s", target, w.Code, w.Body.String(), want)
+		}
+	}
+}
+
+func TestSparseFieldsRejectsUnknownFieldsBeforeTypedHandlers(t *testing.T) {
+	for _, target := range []string{"/dto?fields=nope", "/typed?fields=items.nope", "/typed?fields[nope]=id"} {
+		var calls int
+		w := sparseGet(sparseRouter(&calls), target, "")
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: status %d", target, w.Code)
+		}
+		if calls != 0 {
+			t.Fatalf("%s: handler ran before the selection was rejected", target)
+		}
+	}
+}
+
+func TestSparseFieldsRejectsUnknownFieldsOnWrite(t *testing.T) {
+	var calls int
+	w := sparseGet(sparseRouter(&calls), "/untyped?fields=id,nope", "")
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "INVALID_FIELDS|nope") {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestSparseFieldsKeepsTypesWithMsgPack(t *testing.T) {
+	var calls int
+	w := sparseGet(sparseRouter(&calls), "/dto?fields=id,name", MediaTypeMsgPack)
+	if ct := w.Header().Get("Content-Type"); ct != MediaTypeMsgPack {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var env struct {
+		Data []map[string]interface{} `msgpack:"data"`
+	}
+	if err := msgpack.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 1 || len(env.Data[0]) != 2 {
+		t.Fatalf("data = %#v", env.Data)
+	}
+	switch id := env.Data[0]["id"].(type) {
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+	default:
+		t.Fatalf("id decoded as %T, want an integer", id)
+	}
+}
+
+func TestSparseFieldsKeepsTypesWithCBOR(t *testing.T) {
+	var calls int
+	w := sparseGet(sparseRouter(&calls), "/dto?fields=id", MediaTypeCBOR)
+	var env struct {
+		Data []map[string]interface{} `cbor:"data"`
+	}
+	if err := cbor.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := env.Data[0]["id"].(uint64); !ok || id != 1 {
+		t.Fatalf("id = %#v", env.Data[0]["id"])
+	}
+}
+
+func TestSparseFieldsWithXML(t *testing.T) {
+	var calls int
+	w := sparseGet(sparseRouter(&calls), "/dto?fields=id,items.sku", MediaTypeXML)
+	if ct := w.Header().Get("Content-Type"); ct != MediaTypeXML {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	want := `<data><item><id>1</id><items><item><sku>x</sku></item><item><sku>y</sku></item></items></item></data>`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Fatalf("body = %s", w.Body.String())
+	}
+}
@@ -0,0 +1,274 @@
+package kyugo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+// ErrStreamingUnsupported is returned by the streaming helpers when the
+// response writer cannot flush, for example inside a Timeout. Register
+// event streams with Router.SSE, or give their route `.Timeout(0)`.
+var ErrStreamingUnsupported = errors.New("kyugo: response writer does not support flushing")
+
+// DefaultSSEHeartbeat is the heartbeat period used when
+// SSEOptions.Heartbeat is zero.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// SSEOptions configures Response.SSE.
+type SSEOptions struct {
+	// Heartbeat is the period of the comment lines keeping idle
+	// connections (and proxies) alive. Defaults to DefaultSSEHeartbeat; a
+	// negative value disables heartbeats.
+	Heartbeat time.Duration
+	// Retry, when set, is sent first as the client's reconnection delay.
+	Retry time.Duration
+}
+
+// Event is one Server-Sent Event. Data is sent as is when it is a string
+// or []byte and JSON-encoded otherwise; multi-line strings become several
+// data lines.
+type Event struct {
+	ID    string
+	Event string
+	Data  interface{}
+	// Retry updates the client's reconnection delay when positive.
+	Retry time.Duration
+}
+
+// EventStream writes Server-Sent Events to one client. Its methods may be
+// called from several goroutines; they fail once the client disconnected.
+type EventStream struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	ctx    context.Context
+	cancel context.CancelFunc
+	lastID string
+	mu     sync.Mutex
+}
+
+// sseHeaders are set on event streams.
+var sseHeaders = map[string]string{
+	"Content-Type":      "text/event-stream",
+	"Cache-Control":     "no-cache",
+	"Connection":        "keep-alive",
+	"X-Accel-Buffering": "no",
+}
+
+// SSE streams Server-Sent Events produced by fn. The response headers are
+// sent and flushed first, then fn runs until it returns or the client
+// disconnects, which cancels stream.Context(). Comment heartbeats are
+// sent while fn runs (see SSEOptions). Clients reconnecting with a
+// Last-Event-ID header can be resumed from stream.LastEventID().
+//
+//	func (c *OrderController) Status(resp *kyugo.Response, req *kyugo.Request) error {
+//		return resp.SSE(func(stream *kyugo.EventStream) error {
+//			updates := c.orders.Subscribe(stream.Context(), req.Param("id"), stream.LastEventID())
+//			for u := range updates {
+//				if err := stream.Send(kyugo.Event{ID: u.ID, Event: "status", Data: u}); err != nil {
+//					return err
+//				}
+//			}
+//			return nil
+//		})
+//	}
+//
+// SSE returns ErrStreamingUnsupported, before writing anything, when the
+// writer cannot flush, as under a route or group Timeout; Router.SSE
+// registers routes without one. Once streaming started the status can no longer
+// change, so errors from fn are logged (unless the client went away) and
+// SSE returns nil.
+func (resp *Response) SSE(fn func(stream *EventStream) error, opts ...SSEOptions) error {
+	var o SSEOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Heartbeat == 0 {
+		o.Heartbeat = DefaultSSEHeartbeat
+	}
+
+	stream, err := startEventStream(resp.W, resp.R)
+	if err != nil {
+		return err
+	}
+	defer stream.cancel()
+	if o.Retry > 0 {
+		if err := stream.write("retry: " + strconv.FormatInt(o.Retry.Milliseconds(), 10) + "\n\n"); err != nil {
+			return nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	if o.Heartbeat > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream.heartbeat(o.Heartbeat, done)
+		}()
+	}
+	err = fn(stream)
+	close(done)
+	wg.Wait()
+
+	if err != nil && stream.ctx.Err() == nil {
+		logger.Error("HTTP.SSE", logger.Fields{"method": resp.R.Method, "path": resp.R.URL.Path, "error": err.Error()})
+	}
+	return nil
+}
+
+// SSEHandler produces the events of one client of an SSE route.
+type SSEHandler func(stream *EventStream, req *Request) error
+
+// SSE registers a GET route streaming the Server-Sent Events produced by
+// h, like Response.SSE. The route runs its middleware and validation like
+// any other; timeouts inherited from groups do not apply since they would
+// buffer, then cut, the stream.
+//
+//	api := r.Group("/api").Timeout(5 * time.Second)
+//	api.SSE("/orders/{id}/status", func(stream *kyugo.EventStream, req *kyugo.Request) error {
+//		for u := range orders.Subscribe(stream.Context(), req.Param("id"), stream.LastEventID()) {
+//			if err := stream.Send(kyugo.Event{ID: u.ID, Event: "status", Data: u}); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+func (rt *Router) SSE(p string, h SSEHandler, opts ...SSEOptions) *RouteChain {
+	return rt.Group("/").SSE(p, h, opts...)
+}
+
+// SSE registers an SSE route on the group. See Router.SSE.
+func (g *Group) SSE(p string, h SSEHandler, opts ...SSEOptions) *RouteChain {
+	rc := g.Get(p, func(resp *Response, req *Request) error {
+		return resp.SSE(func(stream *EventStream) error {
+			return h(stream, req)
+		}, opts...)
+	})
+	return rc.update(func(r *route) {
+		r.handler = funcName(h)
+		r.timeout = 0
+	})
+}
+
+func startEventStream(w http.ResponseWriter, r *http.Request) (*EventStream, error) {
+	h := w.Header()
+	for k, v := range sseHeaders {
+		h.Set(k, v)
+	}
+	h.Del("Content-Length")
+	rc := http.NewResponseController(w)
+	// Flush sends the headers, or reports the writer cannot stream
+	if err := rc.Flush(); err != nil {
+		for k := range sseHeaders {
+			h.Del(k)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrStreamingUnsupported, err)
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	return &EventStream{
+		w:      w,
+		rc:     rc,
+		ctx:    ctx,
+		cancel: cancel,
+		lastID: r.Header.Get("Last-Event-ID"),
+	}, nil
+}
+
+// Context is canceled when the client disconnects or a write fails.
+func (s *EventStream) Context() context.Context { return s.ctx }
+
+// LastEventID returns the Last-Event-ID sent by a reconnecting client, or
+// "" on the first connection.
+func (s *EventStream) LastEventID() string { return s.lastID }
+
+// Send writes e and flushes it.
+func (s *EventStream) Send(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n\x00") || strings.ContainsAny(e.Event, "\r\n") {
+		return errors.New("kyugo: event id and name must be single-line")
+	}
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	var data string
+	switch v := e.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		enc, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(enc)
+	}
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// SendData sends data as an unnamed event.
+func (s *EventStream) SendData(data interface{}) error {
+	return s.Send(Event{Data: data})
+}
+
+// Comment writes a comment line, ignored by clients.
+func (s *EventStream) Comment(text string) error {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r", " "), "\n", " ")
+	return s.write(": " + text + "\n\n")
+}
+
+func (s *EventStream) write(chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.w.Write([]byte(chunk)); err != nil {
+		s.cancel()
+		return err
+	}
+	if err := s.rc.Flush(); err != nil {
+		s.cancel()
+		return err
+	}
+	return nil
+}
+
+func (s *EventStream) heartbeat(every time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-t.C:
+			if s.Comment("heartbeat") != nil {
+				return
+			}
+		}
+	}
+}
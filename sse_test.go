@@ -0,0 +1,139 @@
+package kyugo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEWritesEvents(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/events", func(resp *Response, req *Request) error {
+		return resp.SSE(func(stream *EventStream) error {
+			if err := stream.Send(Event{ID: "1", Event: "greeting", Data: "hello\nworld"}); err != nil {
+				return err
+			}
+			if err := stream.SendData(map[string]int{"n": 2}); err != nil {
+				return err
+			}
+			return stream.Send(Event{ID: "bad\nid"})
+		}, SSEOptions{Retry: 3 * time.Second, Heartbeat: -1})
+	})
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Fatalf("Cache-Control = %q", cc)
+	}
+	want := "retry: 3000\n\n" +
+		"id: 1\nevent: greeting\ndata: hello\ndata: world\n\n" +
+		"data: {\"n\":2}\n\n"
+	if got := w.Body.String(); got != want {
+		t.Fatalf("body =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSSELastEventIDAndHeartbeat(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/events", func(resp *Response, req *Request) error {
+		return resp.SSE(func(stream *EventStream) error {
+			if err := stream.SendData("resume after " + stream.LastEventID()); err != nil {
+				return err
+			}
+			<-stream.Context().Done()
+			return nil
+		}, SSEOptions{Heartbeat: 10 * time.Millisecond})
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	sc := bufio.NewScanner(res.Body)
+	var lines []string
+	for sc.Scan() && len(lines) < 4 {
+		if sc.Text() != "" {
+			lines = append(lines, sc.Text())
+		}
+	}
+	if len(lines) < 2 || lines[0] != "data: resume after 41" || lines[1] != ": heartbeat" {
+		t.Fatalf("stream lines = %q", lines)
+	}
+}
+
+type noFlushWriter struct{ http.ResponseWriter }
+
+func TestSSEUnsupportedWriter(t *testing.T) {
+	called := false
+	var err error
+	rt := NewRouter()
+	rt.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		resp := &Response{W: noFlushWriter{w}, R: r}
+		err = resp.SSE(func(stream *EventStream) error {
+			called = true
+			return nil
+		})
+	})
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !errors.Is(err, ErrStreamingUnsupported) || called {
+		t.Fatalf("SSE = %v, fn called %v", err, called)
+	}
+	if strings.Contains(w.Header().Get("Content-Type"), "event-stream") {
+		t.Fatal("SSE headers left on a failed stream")
+	}
+}
+
+func TestSSERouteIgnoresGroupTimeout(t *testing.T) {
+	rt := NewRouter()
+	api := rt.Group("/api").Timeout(20 * time.Millisecond)
+	api.SSE("/events/{topic}", func(stream *EventStream, req *Request) error {
+		if err := stream.SendData("topic " + req.Param("topic")); err != nil {
+			return err
+		}
+		// outlive the group deadline
+		time.Sleep(40 * time.Millisecond)
+		return stream.SendData("still here")
+	}, SSEOptions{Heartbeat: -1})
+	api.Get("/plain", func(resp *Response, req *Request) error {
+		return resp.SSE(func(stream *EventStream) error { return nil })
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/api/events/news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(res.Body)
+	if want := "data: topic news\n\ndata: still here\n\n"; string(body) != want {
+		t.Fatalf("body = %q, want %q", body, want)
+	}
+
+	res, err = http.Get(srv.URL + "/api/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Response.SSE under a group timeout: status %d, want 500", res.StatusCode)
+	}
+}
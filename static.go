@@ -0,0 +1,198 @@
+package kyugo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// StaticOptions configures Router.Static.
+type StaticOptions struct {
+	// Index is served for directory requests. Defaults to "index.html".
+	Index string
+	// CacheControl, when set, is sent with every file served.
+	CacheControl string
+	// SPA serves the root Index for GET/HEAD requests whose path matches no
+	// file and has no extension, so client-side routes survive reloads.
+	SPA bool
+}
+
+// Static serves the directory tree of fsys under prefix for GET and HEAD
+// requests. Responses carry ETag and Last-Modified headers and honour
+// conditional (If-None-Match, If-Modified-Since) and Range requests. When
+// the client accepts gzip and a precompressed "<file>.gz" sibling exists,
+// it is served with Content-Encoding: gzip. Missing files get the router's
+// 404 response.
+//
+//	docs, _ := fs.Sub(kyugo.ResourcesFS(), "docs")
+//	r.Static("/docs", docs, kyugo.StaticOptions{CacheControl: "public, max-age=300"})
+//	r.Static("/", dist, kyugo.StaticOptions{SPA: true}) // dist is an embed.FS sub tree
+//
+// Every file of fsys is published, so pass a sub tree (fs.Sub) rather than
+// a whole resources FS holding language files or other private data.
+func (rt *Router) Static(prefix string, fsys fs.FS, opts ...StaticOptions) *RouteChain {
+	var o StaticOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Index == "" {
+		o.Index = "index.html"
+	}
+	s := &staticServer{fsys: fsys, opts: o, router: rt}
+
+	methods := []string{http.MethodGet, http.MethodHead}
+	prefix = "/" + strings.Trim(prefix, "/")
+	pattern := strings.TrimSuffix(prefix, "/") + "/*"
+	rc := rt.Match(methods, pattern, http.HandlerFunc(s.serve))
+	if prefix != "/" {
+		// like http.FileServer, redirect the bare prefix to the directory
+		redirect := rt.Match(methods, prefix, http.HandlerFunc(redirectSlash))
+		rc.keys = append(rc.keys, redirect.keys...)
+	}
+	return rc
+}
+
+type staticServer struct {
+	fsys   fs.FS
+	opts   StaticOptions
+	router *Router
+	etags  sync.Map // name + size + modtime -> ETag
+}
+
+func (s *staticServer) serve(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + chi.URLParam(r, "*"))[1:]
+	if name == "" {
+		name = "."
+	}
+
+	if fi, err := fs.Stat(s.fsys, name); err == nil && fi.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			redirectSlash(w, r)
+			return
+		}
+		name = path.Join(name, s.opts.Index)
+	}
+	if s.serveFile(w, r, name) {
+		return
+	}
+	if s.opts.SPA && path.Ext(name) == "" && s.serveFile(w, r, s.opts.Index) {
+		return
+	}
+	s.router.notFound(w, r)
+}
+
+// serveFile writes name, or its precompressed sibling, and reports whether
+// the file exists.
+func (s *staticServer) serveFile(w http.ResponseWriter, r *http.Request, name string) bool {
+	if acceptsGzip(r) {
+		if f, fi, ok := s.open(name + ".gz"); ok {
+			defer f.Close()
+			w.Header().Set("Content-Encoding", "gzip")
+			addVary(w.Header(), "Accept-Encoding")
+			s.write(w, r, name, name+".gz", f, fi)
+			return true
+		}
+	}
+	f, fi, ok := s.open(name)
+	if !ok {
+		return false
+	}
+	defer f.Close()
+	if s.exists(name + ".gz") {
+		addVary(w.Header(), "Accept-Encoding")
+	}
+	s.write(w, r, name, name, f, fi)
+	return true
+}
+
+func (s *staticServer) open(name string) (fs.File, fs.FileInfo, bool) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, nil, false
+	}
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		f.Close()
+		return nil, nil, false
+	}
+	return f, fi, true
+}
+
+func (s *staticServer) exists(name string) bool {
+	fi, err := fs.Stat(s.fsys, name)
+	return err == nil && !fi.IsDir()
+}
+
+// write serves f (stored as stored) under the content type of name.
+func (s *staticServer) write(w http.ResponseWriter, r *http.Request, name, stored string, f fs.File, fi fs.FileInfo) {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		rs = bytes.NewReader(b)
+	}
+	etag, err := s.etag(stored, fi, rs)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	if s.opts.CacheControl != "" {
+		w.Header().Set("Cache-Control", s.opts.CacheControl)
+	}
+	// ServeContent handles HEAD, Range, If-None-Match and If-Modified-Since
+	http.ServeContent(w, r, path.Base(name), fi.ModTime(), rs)
+}
+
+// etag returns a strong ETag from the content hash of the file, computed
+// once per name, size and modification time.
+func (s *staticServer) etag(name string, fi fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	key := name + "\x00" + fi.ModTime().UTC().Format(time.RFC3339Nano) + "\x00" + strconv.FormatInt(fi.Size(), 10)
+	if v, ok := s.etags.Load(key); ok {
+		return v.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(key, etag)
+	return etag, nil
+}
+
+// redirectSlash redirects a directory request to its path with a trailing
+// slash so relative links in the index resolve.
+func redirectSlash(w http.ResponseWriter, r *http.Request) {
+	u := *r.URL
+	u.Path += "/"
+	http.Redirect(w, r, u.RequestURI(), http.StatusMovedPermanently)
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		params = strings.ReplaceAll(params, " ", "")
+		return params != "q=0" && params != "q=0.0" && params != "q=0.00" && params != "q=0.000"
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package kyugo
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var staticFiles = fstest.MapFS{
+	"index.html":        {Data: []byte("<h1>home</h1>"), ModTime: time.Unix(1700000000, 0)},
+	"app.js":            {Data: []byte("console.log('plain')"), ModTime: time.Unix(1700000000, 0)},
+	"app.js.gz":         {Data: []byte("gzipped bytes"), ModTime: time.Unix(1700000000, 0)},
+	"guide/index.html":  {Data: []byte("<h1>guide</h1>"), ModTime: time.Unix(1700000000, 0)},
+	"guide/chapter.txt": {Data: []byte("0123456789"), ModTime: time.Unix(1700000000, 0)},
+}
+
+func staticGet(rt *Router, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	rt.ServeHTTP(w, r)
+	return w
+}
+
+func TestStaticETagAndConditionalGet(t *testing.T) {
+	rt := NewRouter()
+	rt.Static("/assets", staticFiles, StaticOptions{CacheControl: "public, max-age=60"})
+
+	w := staticGet(rt, "/assets/guide/chapter.txt", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Body.String() != "0123456789" {
+		t.Fatalf("status %d, ETag %q, body %q", w.Code, etag, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "public, max-age=60" || w.Header().Get("Last-Modified") == "" {
+		t.Fatalf("headers = %v", w.Header())
+	}
+	if w := staticGet(rt, "/assets/guide/chapter.txt", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match: status %d, want 304", w.Code)
+	}
+	w = staticGet(rt, "/assets/guide/chapter.txt", http.Header{"Range": {"bytes=2-4"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" || w.Header().Get("Content-Range") != "bytes 2-4/10" {
+		t.Fatalf("Range: status %d, body %q, Content-Range %q", w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+	}
+}
+
+func TestStaticPrecompressedSibling(t *testing.T) {
+	rt := NewRouter()
+	rt.Static("/assets", staticFiles)
+
+	w := staticGet(rt, "/assets/app.js", http.Header{"Accept-Encoding": {"br, gzip"}})
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Body.String() != "gzipped bytes" {
+		t.Fatalf("gzip: encoding %q, body %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Fatalf("gzip: Content-Type = %q", ct)
+	}
+	if v := w.Header().Values("Vary"); len(v) != 1 || v[0] != "Accept-Encoding" {
+		t.Fatalf("gzip: Vary = %q", v)
+	}
+
+	w = staticGet(rt, "/assets/app.js", http.Header{"Accept-Encoding": {"gzip;q=0"}})
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "console.log('plain')" {
+		t.Fatalf("identity: encoding %q, body %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("identity: Vary = %q", w.Header().Get("Vary"))
+	}
+	// a different ETag keeps caches from mixing both representations
+	plain := staticGet(rt, "/assets/app.js", nil).Header().Get("ETag")
+	gz := staticGet(rt, "/assets/app.js", http.Header{"Accept-Encoding": {"gzip"}}).Header().Get("ETag")
+	if plain == gz {
+		t.Fatal("gzip and identity responses share an ETag")
+	}
+}
+
+func TestStaticDirectories(t *testing.T) {
+	rt := NewRouter()
+	rt.Static("/assets", staticFiles)
+
+	for target, location := range map[string]string{
+		"/assets":       "/assets/",
+		"/assets/guide": "/assets/guide/",
+	} {
+		w := staticGet(rt, target, nil)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != location {
+			t.Errorf("%s: status %d, Location %q", target, w.Code, w.Header().Get("Location"))
+		}
+	}
+	if w := staticGet(rt, "/assets/guide/", nil); w.Body.String() != "<h1>guide</h1>" {
+		t.Fatalf("directory index = %q", w.Body.String())
+	}
+	w := staticGet(rt, "/assets/missing.txt", nil)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"status":"error"`) {
+		t.Fatalf("missing file: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticSPAFallback(t *testing.T) {
+	rt := NewRouter()
+	rt.Static("/", staticFiles, StaticOptions{SPA: true})
+
+	if w := staticGet(rt, "/orders/42/edit", nil); w.Code != http.StatusOK || w.Body.String() != "<h1>home</h1>" {
+		t.Fatalf("client route: status %d, body %q", w.Code, w.Body.String())
+	}
+	if w := staticGet(rt, "/missing.css", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing asset: status %d, want 404", w.Code)
+	}
+}
+
+// brokenFS opens files that fail when read.
+type brokenFS struct{}
+
+type brokenFile struct{ fs.FileInfo }
+
+func (brokenFS) Open(name string) (fs.File, error) {
+	if name != "broken.txt" {
+		return nil, fs.ErrNotExist
+	}
+	fi, _ := fs.Stat(staticFiles, "app.js")
+	return brokenFile{fi}, nil
+}
+
+func (f brokenFile) Stat() (fs.FileInfo, error) { return f.FileInfo, nil }
+func (brokenFile) Read([]byte) (int, error)     { return 0, errors.New("disk on fire") }
+func (brokenFile) Close() error                 { return nil }
+
+func TestStaticReadErrorUsesErrorEnvelope(t *testing.T) {
+	rt := NewRouter()
+	rt.Static("/", brokenFS{})
+	w := staticGet(rt, "/broken.txt", nil)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"status":"error"`) {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+}
+
+func TestResourcesFS(t *testing.T) {
+	prev := Resources
+	defer func() {
+		Resources = prev
+		resetResourceDirs()
+	}()
+	Resources = map[string][]byte{
+		"langs/en-US/app.json": []byte(`{"hello":"Hello"}`),
+		"docs/index.html":      []byte("<h1>docs</h1>"),
+		"docs/api/v1.md":       []byte("# v1"),
+		"robots.txt":           []byte("User-agent: *"),
+	}
+	resetResourceDirs()
+
+	if err := fstest.TestFS(ResourcesFS(), "langs/en-US/app.json", "docs/index.html", "docs/api/v1.md", "robots.txt"); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := fs.Sub(ResourcesFS(), "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(docs, "index.html", "api/v1.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResourcesFS().Open("docs/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open(missing) = %v", err)
+	}
+
+	// files added after the index was built are picked up
+	Resources["docs/new.md"] = []byte("new")
+	if b, err := fs.ReadFile(docs, "new.md"); err != nil || string(b) != "new" {
+		t.Fatalf("ReadFile(new.md) = %q, %v", b, err)
+	}
+	entries, err := fs.ReadDir(docs, ".")
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("ReadDir(docs) = %v, %v", entries, err)
+	}
+}
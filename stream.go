@@ -0,0 +1,330 @@
+package kyugo
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+// MediaTypeNDJSON is the content type of newline-delimited JSON streams.
+const MediaTypeNDJSON = "application/x-ndjson"
+
+// StreamFormat selects how streamed items are written.
+type StreamFormat int
+
+const (
+	// StreamAuto writes NDJSON when the Accept header names
+	// application/x-ndjson with a quality at least that of
+	// application/json, and a JSON array otherwise.
+	StreamAuto StreamFormat = iota
+	// StreamJSONArray writes the items as the `data` array of a success
+	// envelope.
+	StreamJSONArray
+	// StreamNDJSON writes one JSON item per line.
+	StreamNDJSON
+)
+
+// StreamOptions configures the streaming helpers.
+//
+// Routes with a Timeout (their own or their group's) buffer the whole
+// response in memory until the handler returns, so nothing is streamed;
+// give streaming routes `.Timeout(0)` and rely on the request context
+// instead.
+type StreamOptions struct {
+	Format StreamFormat
+	// Code and Message fill the success envelope; Code defaults to 200.
+	Code    int
+	Message string
+	// FlushEvery flushes after that many items (default 100) and
+	// FlushInterval at least that often while items arrive (default 1s).
+	FlushEvery    int
+	FlushInterval time.Duration
+}
+
+// StreamSeq writes the items of seq incrementally instead of buffering the
+// whole data value like SuccessResponse. As a JSON array the output is a
+// regular success envelope:
+//
+//	{"status":"success","code":200,"message":"...","data":[{...},{...}]}
+//
+// As NDJSON each item is a line. Output is flushed periodically (see
+// StreamOptions) and iteration stops as soon as the client disconnects.
+//
+// An error ending the stream is rendered by the router's error handler when
+// nothing was written yet: StreamSeq2 and StreamRowsAs return it for the
+// handler to return. Once items were sent the status can no longer change,
+// so the error is logged and reported after the items: in a trailing
+// `error` field of the envelope, or as a final ErrorEnvelope line in NDJSON.
+func StreamSeq[T any](resp *Response, seq iter.Seq[T], opts ...StreamOptions) {
+	err := StreamSeq2(resp, func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}, opts...)
+	if err != nil {
+		handleError(resp.W, resp.R, err)
+	}
+}
+
+// StreamSeq2 streams the items of seq like StreamSeq; the first non-nil
+// error ends the stream. The returned error is non-nil only when it
+// occurred before anything was written, so the handler can return it:
+//
+//	func (c *ExportController) Orders(resp *kyugo.Response, req *kyugo.Request) error {
+//		return kyugo.StreamSeq2(resp, c.orders.All(req.R.Context()))
+//	}
+func StreamSeq2[T any](resp *Response, seq iter.Seq2[T, error], opts ...StreamOptions) error {
+	s := newStreamWriter(resp, opts)
+	ctx := resp.R.Context()
+	for v, err := range seq {
+		var b []byte
+		if err == nil {
+			b, err = json.Marshal(v)
+		}
+		if err != nil {
+			return s.finish(err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := s.item(b); err != nil {
+			// the client went away
+			return nil
+		}
+	}
+	return s.finish(nil)
+}
+
+// StreamRowsAs streams rows, scanning each with scan, and closes rows. Run
+// the query with the request context so a disconnect cancels it. Errors
+// are handled like StreamSeq2.
+//
+//	rows, err := db.SQL.QueryContext(req.R.Context(), "SELECT id, name FROM products")
+//	...
+//	return kyugo.StreamRowsAs(resp, rows, func(rows *sql.Rows) (Product, error) {
+//		var p Product
+//		return p, rows.Scan(&p.ID, &p.Name)
+//	})
+func StreamRowsAs[T any](resp *Response, rows *sql.Rows, scan func(*sql.Rows) (T, error), opts ...StreamOptions) error {
+	return StreamSeq2(resp, func(yield func(T, error) bool) {
+		defer rows.Close()
+		for rows.Next() {
+			v, err := scan(rows)
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}, opts...)
+}
+
+// StreamRows streams rows as JSON objects keyed by column name and closes
+// rows. See StreamRowsAs.
+func (resp *Response) StreamRows(rows *sql.Rows, opts ...StreamOptions) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return err
+	}
+	return StreamRowsAs(resp, rows, func(rows *sql.Rows) (map[string]interface{}, error) {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, len(cols))
+		for i, c := range cols {
+			if b, ok := vals[i].([]byte); ok {
+				vals[i] = string(b)
+			}
+			m[c] = vals[i]
+		}
+		return m, nil
+	}, opts...)
+}
+
+type streamWriter struct {
+	resp      *Response
+	opts      StreamOptions
+	ndjson    bool
+	bw        *bufio.Writer
+	rc        *http.ResponseController
+	started   bool
+	pending   int
+	lastFlush time.Time
+}
+
+func newStreamWriter(resp *Response, opts []StreamOptions) *streamWriter {
+	var o StreamOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Code == 0 {
+		o.Code = http.StatusOK
+	}
+	if o.FlushEvery <= 0 {
+		o.FlushEvery = 100
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Second
+	}
+	ndjson := o.Format == StreamNDJSON
+	if o.Format == StreamAuto && resp.R != nil {
+		ndjson = acceptsNDJSON(resp.R)
+	}
+	return &streamWriter{resp: resp, opts: o, ndjson: ndjson}
+}
+
+// acceptsNDJSON reports whether r's Accept header names NDJSON and prefers
+// it to JSON.
+func acceptsNDJSON(r *http.Request) bool {
+	ranges := parseAccept(strings.Join(r.Header.Values("Accept"), ","))
+	q, exact := acceptQuality(ranges, MediaTypeNDJSON)
+	if !exact || q <= 0 {
+		return false
+	}
+	jq, _ := acceptQuality(ranges, MediaTypeJSON)
+	return q >= jq
+}
+
+// start writes the headers and, for arrays, the envelope up to `[`.
+func (s *streamWriter) start() error {
+	s.started = true
+	s.bw = bufio.NewWriterSize(s.resp.W, 32<<10)
+	s.rc = http.NewResponseController(s.resp.W)
+	s.lastFlush = time.Now()
+	h := s.resp.W.Header()
+	h.Del("Content-Length")
+	if s.ndjson {
+		h.Set("Content-Type", MediaTypeNDJSON)
+		s.resp.W.WriteHeader(http.StatusOK)
+		return nil
+	}
+	h.Set("Content-Type", MediaTypeJSON)
+	s.resp.W.WriteHeader(http.StatusOK)
+	_, err := s.bw.WriteString(s.head())
+	return err
+}
+
+// head returns the array envelope up to the opening `[` of its data,
+// writing the fields of SuccessEnvelope that precede it.
+func (s *streamWriter) head() string {
+	b := `{"status":"success","code":` + strconv.Itoa(s.opts.Code)
+	if s.opts.Message != "" {
+		msg, _ := json.Marshal(s.opts.Message)
+		b += `,"message":` + string(msg)
+	}
+	return b + `,"data":[`
+}
+
+// item writes one encoded item.
+func (s *streamWriter) item(b []byte) error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	} else if !s.ndjson {
+		b = append([]byte{','}, b...)
+	}
+	if s.ndjson {
+		b = append(b, '\n')
+	}
+	if _, err := s.bw.Write(b); err != nil {
+		return err
+	}
+	s.pending++
+	if s.pending >= s.opts.FlushEvery || time.Since(s.lastFlush) >= s.opts.FlushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *streamWriter) flush() error {
+	s.pending = 0
+	s.lastFlush = time.Now()
+	if err := s.bw.Flush(); err != nil {
+		return err
+	}
+	// writers that cannot flush (for example under Timeout) still get the
+	// complete output when the handler returns
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// finish closes the stream, reporting err after the items written so far.
+// It returns err when nothing was written.
+func (s *streamWriter) finish(err error) error {
+	if err != nil && !s.started {
+		return err
+	}
+	if !s.started {
+		if err := s.start(); err != nil {
+			return nil
+		}
+	}
+
+	var tail []byte
+	if err != nil {
+		status, body := s.errorBody(err)
+		if s.ndjson {
+			tail, _ = json.Marshal(ErrorEnvelope{Status: "error", Code: status, Error: body})
+			tail = append(tail, '\n')
+		} else {
+			b, _ := json.Marshal(body)
+			tail = append([]byte(`],"error":`), b...)
+		}
+	} else if !s.ndjson {
+		tail = []byte("]")
+	}
+	if !s.ndjson {
+		tail = append(tail, "}\n"...)
+	}
+	if _, werr := s.bw.Write(tail); werr == nil {
+		_ = s.flush()
+	}
+	return nil
+}
+
+// errorBody describes err like the default error handler would, logging
+// server errors.
+func (s *streamWriter) errorBody(err error) (int, ErrorBody) {
+	r := s.resp.R
+	he := &HTTPError{Status: http.StatusInternalServerError, Code: "INTERNAL_ERROR", Type: "STREAM_ERROR", MessageKey: "locale.internal_error", Err: err}
+	errors.As(err, &he)
+	status := he.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if status >= 500 {
+		logger.Error("HTTP.Stream", logger.Fields{"method": r.Method, "path": r.URL.Path, "status": status, "error": err.Error()})
+	}
+	msg := ""
+	if he.MessageKey != "" {
+		msg, _ = Message(r, he.MessageKey)
+	}
+	if msg == "" {
+		msg = he.Message
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return status, ErrorBody{Type: he.Code, Code: he.Type, Message: msg, Meta: he.Meta}
+}
@@ -0,0 +1,148 @@
+package kyugo
+
+import (
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func failingSeq(n int, err error) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for i := 1; i <= n; i++ {
+			if !yield(i, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(0, err)
+		}
+	}
+}
+
+func serveStream(t *testing.T, accept string, h func(resp *Response, req *Request) error) *httptest.ResponseRecorder {
+	t.Helper()
+	rt := NewRouter()
+	rt.Get("/items", h)
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, req)
+	return w
+}
+
+func TestStreamSeqJSONArray(t *testing.T) {
+	w := serveStream(t, "", func(resp *Response, req *Request) error {
+		StreamSeq(resp, slices.Values([]int{1, 2, 3}), StreamOptions{Message: "ok", FlushEvery: 1})
+		return nil
+	})
+	if ct := w.Header().Get("Content-Type"); ct != MediaTypeJSON {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var env struct {
+		Status  string `json:"status"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    []int  `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	if env.Status != "success" || env.Code != 200 || env.Message != "ok" || !slices.Equal(env.Data, []int{1, 2, 3}) {
+		t.Fatalf("envelope = %+v", env)
+	}
+}
+
+func TestStreamSeqEmptyArray(t *testing.T) {
+	w := serveStream(t, "", func(resp *Response, req *Request) error {
+		return StreamSeq2(resp, failingSeq(0, nil))
+	})
+	if !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Fatalf("body = %s", w.Body.String())
+	}
+}
+
+func TestStreamSeqNDJSON(t *testing.T) {
+	w := serveStream(t, MediaTypeNDJSON, func(resp *Response, req *Request) error {
+		return StreamSeq2(resp, failingSeq(2, nil))
+	})
+	if ct := w.Header().Get("Content-Type"); ct != MediaTypeNDJSON {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if got := w.Body.String(); got != "1\n2\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestStreamErrorBeforeItemsUsesErrorHandler(t *testing.T) {
+	w := serveStream(t, "", func(resp *Response, req *Request) error {
+		return StreamSeq2(resp, failingSeq(0, ErrNotFound))
+	})
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"status":"error"`) {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStreamErrorAfterItemsIsTrailing(t *testing.T) {
+	boom := errors.New("boom")
+
+	w := serveStream(t, "", func(resp *Response, req *Request) error {
+		return StreamSeq2(resp, failingSeq(2, boom))
+	})
+	var env struct {
+		Data  []int     `json:"data"`
+		Error ErrorBody `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusOK || !slices.Equal(env.Data, []int{1, 2}) || env.Error.Type != "INTERNAL_ERROR" {
+		t.Fatalf("status %d, envelope %+v", w.Code, env)
+	}
+
+	w = serveStream(t, MediaTypeNDJSON, func(resp *Response, req *Request) error {
+		return StreamSeq2(resp, failingSeq(1, boom))
+	})
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != "1" || !strings.Contains(lines[1], `"status":"error"`) {
+		t.Fatalf("lines = %q", lines)
+	}
+}
+
+func TestStreamArrayMatchesSuccessEnvelope(t *testing.T) {
+	for _, msg := range []string{"", `say "hi" <now>`} {
+		w := serveStream(t, "", func(resp *Response, req *Request) error {
+			return StreamSeq2(resp, failingSeq(2, nil), StreamOptions{Code: 201, Message: msg})
+		})
+		want, _ := json.Marshal(SuccessEnvelope{Status: "success", Code: 201, Message: msg, Data: []int{1, 2}})
+		if got := strings.TrimSuffix(w.Body.String(), "\n"); got != string(want) {
+			t.Errorf("streamed %s\nbuffered %s", got, want)
+		}
+	}
+}
+
+func TestStreamAutoNegotiatesNDJSON(t *testing.T) {
+	for accept, ndjson := range map[string]bool{
+		"":                            false,
+		"*/*":                         false,
+		MediaTypeNDJSON:               true,
+		MediaTypeNDJSON + ";q=0":      false,
+		MediaTypeNDJSON + ";q=0, */*": false,
+		MediaTypeNDJSON + ";q=0.5, " + MediaTypeJSON: false,
+		MediaTypeJSON + ";q=0.5, " + MediaTypeNDJSON: true,
+		"application/*;q=0.2, " + MediaTypeNDJSON:    true,
+	} {
+		w := serveStream(t, accept, func(resp *Response, req *Request) error {
+			return StreamSeq2(resp, failingSeq(1, nil))
+		})
+		if got := w.Header().Get("Content-Type") == MediaTypeNDJSON; got != ndjson {
+			t.Errorf("Accept %q: NDJSON %v, want %v", accept, got, ndjson)
+		}
+	}
+}
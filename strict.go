@@ -0,0 +1,194 @@
+package kyugo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// DefaultMaxBodySize is the request body limit used when neither the route
+// nor the router configures one.
+const DefaultMaxBodySize int64 = 10 << 20
+
+const strictJSONKey ctxKey = "youu.strict_json"
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+	errMultipleJSONValues = errors.New("json: multiple top-level values")
+)
+
+// Strict enables strict JSON decoding for the previously registered route:
+// bodies holding fields unknown to the DTO, duplicate keys or more than one
+// JSON value are rejected. Unknown and duplicate fields are reported as
+// field errors named by their JSON path (for example `items[0].sku`).
+func (rc *RouteChain) Strict() *RouteChain {
+	return rc.update(func(r *route) { r.strict = true })
+}
+
+// StrictJSON turns strict JSON decoding on or off for every route of the
+// router (see RouteChain.Strict). NewServer sets it from
+// `server.strict_json`.
+func (rt *Router) StrictJSON(on bool) *Router {
+	rt.strictJSON = on
+	return rt
+}
+
+// MaxBodySize sets the body limit, in bytes, of routes declaring none.
+// Zero restores DefaultMaxBodySize and a negative value disables the limit.
+// NewServer sets it from `server.max_body_size_bytes`. Mounted routers
+// inherit the limit.
+func (rt *Router) MaxBodySize(n int64) *Router {
+	rt.maxBodySize = n
+	return rt
+}
+
+// bodyLimit returns the body limit of rt or its closest ancestor.
+func (rt *Router) bodyLimit() int64 {
+	for ; rt != nil; rt = rt.parent {
+		if rt.maxBodySize != 0 {
+			return rt.maxBodySize
+		}
+	}
+	return DefaultMaxBodySize
+}
+
+// strict reports whether rt or an ancestor enables strict decoding.
+func (rt *Router) strict() bool {
+	for ; rt != nil; rt = rt.parent {
+		if rt.strictJSON {
+			return true
+		}
+	}
+	return false
+}
+
+// isStrict reports whether the matched route decodes JSON strictly.
+func isStrict(r *http.Request) bool {
+	on, _ := r.Context().Value(strictJSONKey).(bool)
+	return on
+}
+
+// isJSONCodec reports whether c is the built-in JSON codec, the only one
+// strict mode applies to.
+func isJSONCodec(c Codec) bool {
+	_, ok := c.(jsonCodec)
+	return ok
+}
+
+// checkStrictJSON walks data against t and reports unknown and duplicate
+// fields. A non-nil error means data is not a single JSON value.
+func checkStrictJSON(data []byte, t reflect.Type) ([]FieldError, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	s := &strictWalker{dec: dec}
+	if err := s.value(t, ""); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errMultipleJSONValues
+	}
+	return s.fields, nil
+}
+
+type strictWalker struct {
+	dec    *json.Decoder
+	fields []FieldError
+}
+
+func (s *strictWalker) value(t reflect.Type, path string) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	d, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	t = strictType(t)
+	switch d {
+	case '{':
+		return s.object(t, path)
+	case '[':
+		return s.array(t, path)
+	}
+	return fmt.Errorf("json: unexpected %v", d)
+}
+
+func (s *strictWalker) object(t reflect.Type, path string) error {
+	var known *jsonFields
+	var elem reflect.Type
+	if t != nil {
+		switch t.Kind() {
+		case reflect.Struct:
+			known = jsonFieldsOf(t)
+		case reflect.Map:
+			elem = t.Elem()
+		}
+	}
+	seen := map[string]bool{}
+	for s.dec.More() {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		p := joinJSONPath(path, key)
+		if seen[key] {
+			s.fields = append(s.fields, FieldError{Field: p, Code: "INVALID_DUPLICATE_FIELD", Message: fmt.Sprintf("duplicate field %q", p)})
+		}
+		seen[key] = true
+
+		ft := elem
+		if known != nil {
+			f, found := known.lookup(key)
+			if !found {
+				s.fields = append(s.fields, FieldError{Field: p, Code: "INVALID_UNKNOWN_FIELD", Message: fmt.Sprintf("unknown field %q", p)})
+			}
+			ft = f.typ
+		}
+		if err := s.value(ft, p); err != nil {
+			return err
+		}
+	}
+	_, err := s.dec.Token()
+	return err
+}
+
+func (s *strictWalker) array(t reflect.Type, path string) error {
+	var elem reflect.Type
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		elem = t.Elem()
+	}
+	for i := 0; s.dec.More(); i++ {
+		if err := s.value(elem, path+"["+strconv.Itoa(i)+"]"); err != nil {
+			return err
+		}
+	}
+	_, err := s.dec.Token()
+	return err
+}
+
+// strictType dereferences t and returns nil for types whose JSON shape is
+// not described by their fields (interfaces and json.Unmarshalers).
+func strictType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+	return t
+}
+
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
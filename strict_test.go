@@ -0,0 +1,64 @@
+package kyugo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func readAll(w http.ResponseWriter, r *http.Request) {
+	if _, err := io.ReadAll(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestDefaultBodyLimitAndOptOut(t *testing.T) {
+	body := strings.Repeat("x", int(DefaultMaxBodySize)+1)
+	post := func(rt *Router) int {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/raw", strings.NewReader(body)))
+		return w.Code
+	}
+
+	rt := NewRouter()
+	rt.Post("/raw", readAll)
+	if code := post(rt); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("default limit: status %d, want 413", code)
+	}
+
+	rt = NewRouter().MaxBodySize(-1)
+	rt.Post("/raw", readAll)
+	if code := post(rt); code != http.StatusOK {
+		t.Fatalf("router opt-out: status %d", code)
+	}
+
+	rt = NewRouter()
+	rt.Post("/raw", readAll).MaxBodySize(-1)
+	if code := post(rt); code != http.StatusOK {
+		t.Fatalf("route opt-out: status %d", code)
+	}
+}
+
+func TestStrictRejectsUnknownAndDuplicateFields(t *testing.T) {
+	type item struct {
+		SKU string `json:"sku"`
+	}
+	type order struct {
+		Items []item `json:"items"`
+	}
+	rt := NewRouter()
+	rt.Post("/orders", func(w http.ResponseWriter, r *http.Request) {}).ValidateBody(&order{}).Strict()
+
+	for body, want := range map[string]string{
+		`{"items":[{"sku":"a","qty":1}]}`: "items[0].qty",
+		`{"items":[],"items":[]}`:         "items",
+	} {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body)))
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"field":"`+want+`"`) {
+			t.Errorf("%s: %d %s", body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package kyugo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+// Timeout returns a middleware that runs the next handler with a context
+// deadline of d. Handlers should observe ctx.Done(); when they overrun the
+// deadline the client receives a 503 error envelope using the
+// `locale.timeout` message and any later writes by the handler fail with
+// http.ErrHandlerTimeout. The handler's output is buffered until it
+// returns, so Timeout is not suited to streaming responses.
+//
+// A handler panic is re-raised on the serving goroutine, carrying the
+// handler's stack, so Recoverer reports where it happened. Panics after
+// the deadline can no longer reach the client and are logged instead.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, h: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan *handlerPanic, 1)
+			go func() {
+				defer func() {
+					p := recover()
+					if p == nil {
+						return
+					}
+					hp := &handlerPanic{value: p, stack: debug.Stack()}
+					tw.mu.Lock()
+					defer tw.mu.Unlock()
+					if tw.timedOut {
+						logger.Error("HTTP.Panic", logger.Fields{
+							"method":  r.Method,
+							"path":    r.URL.Path,
+							"panic":   fmt.Sprint(p),
+							"stack":   string(hp.stack),
+							"timeout": true,
+						})
+						return
+					}
+					panicked <- hp
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				p.repanic()
+			case <-done:
+				tw.flushTo(w)
+			case <-ctx.Done():
+				p, finished := tw.expire(done, panicked)
+				if p != nil {
+					p.repanic()
+				}
+				if finished {
+					tw.flushTo(w)
+					return
+				}
+				if ctx.Err() != context.DeadlineExceeded {
+					// the client went away; nobody is listening
+					return
+				}
+				msg, ok := Message(r, "locale.timeout")
+				if !ok || msg == "" {
+					msg = "Request timed out"
+				}
+				WriteError(w, r, http.StatusServiceUnavailable, msg, nil, ErrorExtras{
+					Code: "TIMEOUT",
+					Type: "REQUEST_TIMEOUT",
+				})
+			}
+		})
+	}
+}
+
+// handlerPanic carries a panic recovered on another goroutine together
+// with the stack captured where it happened.
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *handlerPanic) String() string { return fmt.Sprint(p.value) }
+
+// repanic re-raises p. http.ErrAbortHandler is re-raised as is so
+// net/http still aborts the response silently.
+func (p *handlerPanic) repanic() {
+	if p.value == http.ErrAbortHandler {
+		panic(p.value)
+	}
+	panic(p)
+}
+
+// recoveredPanic unwraps a value recovered by a middleware, returning the
+// original panic value and the stack of the goroutine that raised it.
+func recoveredPanic(rec interface{}) (interface{}, []byte) {
+	if p, ok := rec.(*handlerPanic); ok {
+		return p.value, p.stack
+	}
+	return rec, debug.Stack()
+}
+
+// expire marks the response timed out unless the handler already
+// finished or panicked, which select may not have noticed when the
+// deadline fired at the same time. It reports the panic or whether the
+// handler finished.
+func (tw *timeoutWriter) expire(done <-chan struct{}, panicked <-chan *handlerPanic) (*handlerPanic, bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	select {
+	case <-done:
+		return nil, true
+	case p := <-panicked:
+		return p, false
+	default:
+	}
+	tw.timedOut = true
+	return nil, false
+}
+
+// flushTo copies the buffered response to w.
+func (tw *timeoutWriter) flushTo(w http.ResponseWriter) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	dst := w.Header()
+	for k, vv := range tw.h {
+		dst[k] = vv
+	}
+	if !tw.wroteHeader {
+		tw.code = http.StatusOK
+	}
+	w.WriteHeader(tw.code)
+	_, _ = w.Write(tw.buf.Bytes())
+}
+
+// timeoutWriter buffers a handler's response so it can be discarded when
+// the deadline fires first.
+type timeoutWriter struct {
+	w  http.ResponseWriter
+	h  http.Header
+	mu sync.Mutex
+
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	tw.wroteHeader = true
+	tw.code = code
+}
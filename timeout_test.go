@@ -0,0 +1,159 @@
+package kyugo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+func TestTimeoutPassesFinishedResponse(t *testing.T) {
+	h := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("made"))
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "made" || w.Header().Get("X-Test") != "1" {
+		t.Fatalf("status %d, header %v, body %q", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestTimeoutOverrun(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := w.Write([]byte("too late"))
+		lateWrite <- err
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var env ErrorEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	if env.Status != "error" || env.Error.Type != "TIMEOUT" || env.Error.Code != "REQUEST_TIMEOUT" {
+		t.Fatalf("envelope = %+v", env)
+	}
+	select {
+	case err := <-lateWrite:
+		if !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Fatalf("late Write = %v, want http.ErrHandlerTimeout", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler never wrote")
+	}
+	if strings.Contains(w.Body.String(), "too late") {
+		t.Fatal("late output reached the client")
+	}
+}
+
+func TestTimeoutPrefersHandlerFinishedAtDeadline(t *testing.T) {
+	tw := &timeoutWriter{h: make(http.Header)}
+	done := make(chan struct{})
+	close(done)
+	p, finished := tw.expire(done, make(chan *handlerPanic, 1))
+	if p != nil || !finished || tw.timedOut {
+		t.Fatalf("expire = %v, %v; timedOut %v", p, finished, tw.timedOut)
+	}
+
+	panicked := make(chan *handlerPanic, 1)
+	panicked <- &handlerPanic{value: "boom"}
+	p, finished = tw.expire(make(chan struct{}), panicked)
+	if p == nil || finished || tw.timedOut {
+		t.Fatalf("expire = %v, %v; timedOut %v", p, finished, tw.timedOut)
+	}
+
+	p, finished = tw.expire(make(chan struct{}), make(chan *handlerPanic, 1))
+	if p != nil || finished || !tw.timedOut {
+		t.Fatalf("expire = %v, %v; timedOut %v", p, finished, tw.timedOut)
+	}
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("handler exploded")
+}
+
+func TestTimeoutPanicReachesRecovererWithHandlerStack(t *testing.T) {
+	h := Recoverer(true)(Timeout(time.Second)(http.HandlerFunc(panickingHandler)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var env struct {
+		Error struct {
+			Meta struct {
+				Panic string   `json:"panic"`
+				Stack []string `json:"stack"`
+			} `json:"meta"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Error.Meta.Panic != "handler exploded" {
+		t.Fatalf("panic = %q", env.Error.Meta.Panic)
+	}
+	if !strings.Contains(strings.Join(env.Error.Meta.Stack, "\n"), "panickingHandler") {
+		t.Fatalf("stack does not name the panicking handler:\n%s", strings.Join(env.Error.Meta.Stack, "\n"))
+	}
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestTimeoutLogsPanicAfterDeadline(t *testing.T) {
+	var out syncBuffer
+	logger.SetStd(&logger.Logger{Z: zerolog.New(&out)})
+	defer logger.SetStd(logger.NewNop())
+
+	h := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		panic("late explosion")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "late explosion") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(out.String(), "HTTP.Panic") || !strings.Contains(out.String(), "late explosion") {
+		t.Fatalf("late panic was not logged: %q", out.String())
+	}
+}
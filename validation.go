@@ -17,12 +17,14 @@ var validate *v10.Validate
 
 func init() {
 	validate = v10.New()
+	registerFileRules(validate)
 }
 
 // Validate runs struct validation using go-playground/validator.
 func Validate(v interface{}) error {
 	if validate == nil {
 		validate = v10.New()
+		registerFileRules(validate)
 	}
 	return validate.Struct(v)
 }
@@ -30,6 +32,13 @@ func Validate(v interface{}) error {
 // FormatValidationErrors converts validator.ValidationErrors into a slice of FieldError
 // where Code follows the pattern "invalid_<rule>|<param>" when applicable.
 func FormatValidationErrors(err error, v interface{}) []FieldError {
+	return formatValidationErrors(err, v, "json")
+}
+
+// formatValidationErrors is FormatValidationErrors with configurable struct
+// tags used to resolve field names (for example "query" for query string
+// DTOs). The json tag is used as a fallback.
+func formatValidationErrors(err error, v interface{}, tags ...string) []FieldError {
 	if err == nil {
 		return nil
 	}
@@ -52,21 +61,14 @@ func FormatValidationErrors(err error, v interface{}) []FieldError {
 	}
 
 	for _, f := range ve {
-		fieldName := f.Field()
-		// try to resolve json tag name
+		// try to resolve the tagged field name; tag names are reported as
+		// written since they are what the client sent
+		fieldName := strings.ToLower(f.Field())
 		if rt != nil {
-			if sf, found := rt.FieldByName(fieldName); found {
-				tag := sf.Tag.Get("json")
-				if tag != "" {
-					parts := strings.Split(tag, ",")
-					if parts[0] != "" && parts[0] != "-" {
-						fieldName = parts[0]
-					}
-				} else {
-					fieldName = strings.ToLower(fieldName)
+			if sf, found := rt.FieldByName(f.Field()); found {
+				if name, ok := fieldKey(sf, tags...); ok {
+					fieldName = name
 				}
-			} else {
-				fieldName = strings.ToLower(fieldName)
 			}
 		}
 
@@ -78,7 +80,7 @@ func FormatValidationErrors(err error, v interface{}) []FieldError {
 			code = code + "|" + param
 		}
 
-		out = append(out, FieldError{Field: strings.ToLower(fieldName), Code: code, Message: f.Error()})
+		out = append(out, FieldError{Field: fieldName, Code: code, Message: f.Error()})
 	}
 	return out
 }
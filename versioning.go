@@ -0,0 +1,186 @@
+package kyugo
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// VersioningOptions configures how a Router resolves the API version of
+// requests whose path carries no version prefix.
+type VersioningOptions struct {
+	// Default is used when the request names no version. Empty leaves
+	// such requests on unversioned routes only.
+	Default string
+	// Header carries an explicit version. Defaults to "API-Version".
+	Header string
+	// Vendor restricts Accept media types to application/vnd.<Vendor>.vN+json.
+	// When empty any vendor name is accepted.
+	Vendor string
+	// Prefix is prepended to the version to build path prefixes.
+	// Defaults to "/v", giving "/v1", "/v2", ...
+	Prefix string
+}
+
+const versionKey ctxKey = "youu.api_version"
+
+var acceptVersionRe = regexp.MustCompile(`application/vnd\.([A-Za-z0-9._-]+?)\.v([A-Za-z0-9.]+)(\+[a-z]+)?`)
+
+// Versioning enables version resolution on this router. Versioned routes
+// are declared with Router.Version / Group.Version and are always reachable
+// through their path prefix (for example /v2/products). Requests without a
+// prefix are routed to the version named by the API-Version header, then
+// by a vendor media type in Accept (application/vnd.app.v2+json), then by
+// opts.Default; when no versioned route matches, unversioned routes serve
+// the request.
+func (rt *Router) Versioning(opts VersioningOptions) *Router {
+	if opts.Header == "" {
+		opts.Header = "API-Version"
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "/v"
+	}
+	rt.versioning = &opts
+	return rt
+}
+
+// Version returns a group for API version v rooted at the version path
+// prefix (for example "/v2").
+func (rt *Router) Version(v string) *Group {
+	return rt.Group("/").Version(v)
+}
+
+// Version returns a child group for API version v under this group's
+// prefix. Routes registered through it report v via Request.Version. Call
+// Router.Versioning first when using a custom Prefix.
+func (g *Group) Version(v string) *Group {
+	child := g.Group(g.router.versionPrefix() + v)
+	child.version = v
+	g.router.versions[v] = true
+	return child
+}
+
+// Deprecated marks every route subsequently registered through this group
+// as deprecated: responses carry a `Deprecation` header and, when sunset
+// is non-zero, a `Sunset` header.
+func (g *Group) Deprecated(sunset time.Time) *Group {
+	g.deprecated = true
+	g.sunset = sunset
+	return g
+}
+
+// Deprecated marks the previously registered route as deprecated. Its
+// responses carry a `Deprecation` header and, when sunset is non-zero, a
+// `Sunset` header with the date after which the route may be removed.
+func (rc *RouteChain) Deprecated(sunset time.Time) *RouteChain {
+	return rc.update(func(r *route) {
+		r.deprecated = true
+		r.sunset = sunset
+	})
+}
+
+// Version returns the API version of the matched route, or the resolved
+// request version for unversioned routes on a router using Versioning.
+func (r *Request) Version() string {
+	if r == nil || r.R == nil {
+		return ""
+	}
+	v, _ := r.R.Context().Value(versionKey).(string)
+	return v
+}
+
+func (rt *Router) versionPrefix() string {
+	if rt.versioning != nil {
+		return rt.versioning.Prefix
+	}
+	return "/v"
+}
+
+// requestedVersion returns the version named by the request headers or
+// the configured default.
+func (rt *Router) requestedVersion(r *http.Request) string {
+	opts := rt.versioning
+	if v := strings.TrimSpace(r.Header.Get(opts.Header)); v != "" {
+		return strings.TrimPrefix(strings.ToLower(v), "v")
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, m := range acceptVersionRe.FindAllStringSubmatch(accept, -1) {
+			if opts.Vendor == "" || m[1] == opts.Vendor {
+				return m[2]
+			}
+		}
+	}
+	return opts.Default
+}
+
+// routeVersion rewrites the routing path of r to the versioned route
+// matching the requested version, when there is one. Paths that already
+// carry a known version prefix are left untouched.
+func (rt *Router) routeVersion(r *http.Request) *http.Request {
+	opts := rt.versioning
+	rctx := chi.RouteContext(r.Context())
+	p := r.URL.Path
+	if rctx != nil && rctx.RoutePath != "" {
+		p = rctx.RoutePath
+	}
+	if strings.HasPrefix(p, opts.Prefix) {
+		rest := p[len(opts.Prefix):]
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			rest = rest[:i]
+		}
+		if rt.versions[rest] {
+			return r
+		}
+	}
+
+	v := rt.requestedVersion(r)
+	if v == "" {
+		return r
+	}
+	r = r.WithContext(context.WithValue(r.Context(), versionKey, v))
+	candidate := opts.Prefix + v + p
+	if p == "/" {
+		candidate = opts.Prefix + v
+	}
+	if !rt.matchesAnyMethod(candidate) && !rt.hostServes(r, candidate) {
+		return r
+	}
+	if rctx != nil && rctx.RoutePath != "" {
+		rctx.RoutePath = candidate
+		return r
+	}
+	u := *r.URL
+	u.Path = candidate
+	u.RawPath = ""
+	r.URL = &u
+	return r
+}
+
+// matchesAnyMethod reports whether some route serves path.
+func (rt *Router) matchesAnyMethod(path string) bool {
+	for _, m := range AnyMethods {
+		if rt.r.Match(chi.NewRouteContext(), m, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// setVersionHeaders stamps the version and deprecation information of a
+// matched route on the request context and response headers.
+func setVersionHeaders(w http.ResponseWriter, r *http.Request, info route) *http.Request {
+	if info.deprecated {
+		w.Header().Set("Deprecation", "true")
+		if !info.sunset.IsZero() {
+			w.Header().Set("Sunset", info.sunset.UTC().Format(http.TimeFormat))
+		}
+	}
+	if info.version != "" {
+		r = r.WithContext(context.WithValue(r.Context(), versionKey, info.version))
+	}
+	return r
+}
@@ -0,0 +1,141 @@
+package kyugo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func versionedRouter(opts VersioningOptions) *Router {
+	rt := NewRouter().Versioning(opts)
+	echo := func(resp *Response, req *Request) {
+		_, _ = resp.W.Write([]byte("v" + req.Version()))
+	}
+	rt.Get("/items", echo)
+	rt.Version("1").Get("/items", echo)
+	rt.Version("2").Get("/items", echo)
+	rt.Version("2").Get("/reports", echo)
+	return rt
+}
+
+func versionGet(rt *Router, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	rt.ServeHTTP(w, r)
+	return w
+}
+
+func TestVersionByPathPrefix(t *testing.T) {
+	rt := versionedRouter(VersioningOptions{Default: "1"})
+	for target, want := range map[string]string{
+		"/v1/items": "v1",
+		"/v2/items": "v2",
+	} {
+		if got := versionGet(rt, target, http.Header{"Api-Version": {"1"}}).Body.String(); got != want {
+			t.Errorf("GET %s = %q, want %q", target, got, want)
+		}
+	}
+
+	rt = NewRouter().Versioning(VersioningOptions{Prefix: "/api/v"})
+	rt.Version("3").Get("/items", func(resp *Response, req *Request) {
+		_, _ = resp.W.Write([]byte("v" + req.Version()))
+	})
+	if got := versionGet(rt, "/api/v3/items", nil).Body.String(); got != "v3" {
+		t.Fatalf("custom prefix = %q", got)
+	}
+}
+
+func TestVersionByHeader(t *testing.T) {
+	rt := versionedRouter(VersioningOptions{})
+	for header, want := range map[string]string{
+		"2":  "v2",
+		"v1": "v1",
+		"V2": "v2",
+	} {
+		if got := versionGet(rt, "/items", http.Header{"Api-Version": {header}}).Body.String(); got != want {
+			t.Errorf("API-Version: %s = %q, want %q", header, got, want)
+		}
+	}
+
+	rt = versionedRouter(VersioningOptions{Header: "X-Version"})
+	if got := versionGet(rt, "/items", http.Header{"X-Version": {"2"}}).Body.String(); got != "v2" {
+		t.Fatalf("custom header = %q", got)
+	}
+	// the header wins over Accept
+	h := http.Header{"X-Version": {"1"}, "Accept": {"application/vnd.app.v2+json"}}
+	if got := versionGet(rt, "/items", h).Body.String(); got != "v1" {
+		t.Fatalf("header and Accept = %q", got)
+	}
+}
+
+func TestVersionByAcceptMediaType(t *testing.T) {
+	rt := versionedRouter(VersioningOptions{})
+	accept := http.Header{"Accept": {"text/html, application/vnd.app.v2+json;q=0.9"}}
+	if got := versionGet(rt, "/items", accept).Body.String(); got != "v2" {
+		t.Fatalf("vendor Accept = %q", got)
+	}
+
+	rt = versionedRouter(VersioningOptions{Vendor: "acme"})
+	if got := versionGet(rt, "/items", http.Header{"Accept": {"application/vnd.acme.v2+json"}}).Body.String(); got != "v2" {
+		t.Fatalf("matching vendor = %q", got)
+	}
+	if got := versionGet(rt, "/items", accept).Body.String(); got != "v" {
+		t.Fatalf("other vendor = %q, want the unversioned route", got)
+	}
+}
+
+func TestVersionDefault(t *testing.T) {
+	if got := versionGet(versionedRouter(VersioningOptions{Default: "2"}), "/items", nil).Body.String(); got != "v2" {
+		t.Fatalf("default version = %q", got)
+	}
+	if got := versionGet(versionedRouter(VersioningOptions{}), "/items", nil).Body.String(); got != "v" {
+		t.Fatalf("no default = %q, want the unversioned route", got)
+	}
+}
+
+func TestUnknownVersion(t *testing.T) {
+	rt := versionedRouter(VersioningOptions{})
+
+	// an unknown requested version falls back to the unversioned route and
+	// is still reported by Request.Version
+	if w := versionGet(rt, "/items", http.Header{"Api-Version": {"9"}}); w.Code != http.StatusOK || w.Body.String() != "v9" {
+		t.Fatalf("API-Version: 9 on /items: %d %q", w.Code, w.Body.String())
+	}
+	// without an unversioned route there is nothing to serve
+	if w := versionGet(rt, "/reports", http.Header{"Api-Version": {"9"}}); w.Code != http.StatusNotFound {
+		t.Fatalf("API-Version: 9 on /reports: %d, want 404", w.Code)
+	}
+	if w := versionGet(rt, "/v9/items", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("/v9/items: %d, want 404", w.Code)
+	}
+}
+
+func TestDeprecatedRoutes(t *testing.T) {
+	sunset := time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC)
+	rt := NewRouter().Versioning(VersioningOptions{})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	rt.Version("1").Deprecated(sunset).Get("/items", ok)
+	rt.Version("2").Get("/items", ok)
+	rt.Version("2").Get("/legacy", ok).Deprecated(time.Time{})
+
+	w := versionGet(rt, "/v1/items", nil)
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Mon, 01 Mar 2027 00:00:00 GMT" {
+		t.Fatalf("deprecated group: %v", w.Header())
+	}
+	w = versionGet(rt, "/items", http.Header{"Api-Version": {"1"}})
+	if w.Header().Get("Deprecation") != "true" {
+		t.Fatalf("deprecated group via header: %v", w.Header())
+	}
+	w = versionGet(rt, "/v2/items", nil)
+	if w.Header().Get("Deprecation") != "" || w.Header().Get("Sunset") != "" {
+		t.Fatalf("current version: %v", w.Header())
+	}
+	w = versionGet(rt, "/v2/legacy", nil)
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "" {
+		t.Fatalf("deprecated route without sunset: %v", w.Header())
+	}
+}
@@ -0,0 +1,431 @@
+package kyugo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	cfg "github.com/go-kyugo/kyugo/config"
+	logger "github.com/go-kyugo/kyugo/logger"
+)
+
+// Message types accepted by WebSocketConn.WriteMessage and returned by
+// ReadMessage.
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+// DefaultWebSocketReadLimit is the largest incoming message accepted when
+// WebSocketOptions.ReadLimit is zero.
+const DefaultWebSocketReadLimit int64 = 64 << 10
+
+// WebSocketOptions configures a WebSocket route.
+type WebSocketOptions struct {
+	// ReadLimit is the largest incoming message in bytes; bigger messages
+	// close the connection with status 1009. Defaults to
+	// DefaultWebSocketReadLimit.
+	ReadLimit int64
+	// PingInterval is the keepalive ping period. Defaults to 30s; a
+	// negative value disables pings.
+	PingInterval time.Duration
+	// PongWait is how long a connection may stay silent before reads fail.
+	// Defaults to twice PingInterval.
+	PongWait time.Duration
+	// WriteWait bounds every write. Defaults to 10s.
+	WriteWait         time.Duration
+	Subprotocols      []string
+	EnableCompression bool
+	// CheckOrigin replaces the default origin check (see Router.WebSocket).
+	CheckOrigin func(r *http.Request) bool
+}
+
+func (o WebSocketOptions) withDefaults() WebSocketOptions {
+	if o.ReadLimit <= 0 {
+		o.ReadLimit = DefaultWebSocketReadLimit
+	}
+	if o.PingInterval == 0 {
+		o.PingInterval = 30 * time.Second
+	}
+	if o.PongWait <= 0 && o.PingInterval > 0 {
+		o.PongWait = 2 * o.PingInterval
+	}
+	if o.WriteWait <= 0 {
+		o.WriteWait = 10 * time.Second
+	}
+	return o
+}
+
+// WebSocketHandler serves one upgraded connection. The connection is
+// closed when it returns: normally for a nil error or a closed peer,
+// with status 1011 (and a log entry) otherwise.
+type WebSocketHandler func(conn *WebSocketConn, req *Request) error
+
+// WebSocket registers a GET route upgrading requests to WebSocket
+// connections served by h. The route runs its middleware and params
+// validation like any other, so authentication, path params, localization
+// and Component services are available to h through req. Route timeouts
+// do not apply.
+//
+// Browser origins are accepted when they match the request host or an
+// entry of the router's CORS allowed origins ("*", an exact origin or a
+// pattern such as "https://*.example.com"); others get a 403 error
+// envelope. Failed handshakes get a 400.
+//
+//	r.WebSocket("/rooms/{room}", func(conn *kyugo.WebSocketConn, req *kyugo.Request) error {
+//		room := req.Param("room")
+//		hub.Join(room, conn)
+//		for {
+//			var msg ChatMessage
+//			if err := conn.ReadJSON(&msg); err != nil {
+//				return err
+//			}
+//			hub.BroadcastJSON(room, msg)
+//		}
+//	})
+//
+// Connect with websocket.DefaultDialer to an httptest.NewServer running
+// the router to test such routes.
+func (rt *Router) WebSocket(p string, h WebSocketHandler, opts ...WebSocketOptions) *RouteChain {
+	return rt.Group("/").WebSocket(p, h, opts...)
+}
+
+// WebSocket registers a WebSocket route on the group. See Router.WebSocket.
+func (g *Group) WebSocket(p string, h WebSocketHandler, opts ...WebSocketOptions) *RouteChain {
+	var o WebSocketOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o = o.withDefaults()
+	rt := g.router
+	up := &websocket.Upgrader{
+		Subprotocols:      o.Subprotocols,
+		EnableCompression: o.EnableCompression,
+		CheckOrigin: func(r *http.Request) bool {
+			if o.CheckOrigin != nil {
+				return o.CheckOrigin(r)
+			}
+			return rt.checkOrigin(r)
+		},
+		Error: writeUpgradeError,
+	}
+
+	rc := g.Get(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			// the Upgrader already wrote the error response
+			return
+		}
+		c := newWebSocketConn(conn, r, o)
+		c.finish(h(c, c.req))
+	}))
+	return rc.update(func(r *route) {
+		r.handler = funcName(h)
+		r.timeout = 0
+	})
+}
+
+// CorsConfig sets the CORS configuration whose allowed origins are
+// accepted by WebSocket routes. NewServer sets it from `server.cors`.
+// Mounted routers inherit it.
+func (rt *Router) CorsConfig(c cfg.CorsConfig) *Router {
+	rt.cors = &c
+	return rt
+}
+
+// checkOrigin accepts requests without Origin (non-browser clients), from
+// the request host, or from an origin allowed by the CORS configuration.
+func (rt *Router) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for p := rt; p != nil; p = p.parent {
+		if p.cors == nil {
+			continue
+		}
+		for _, allowed := range p.cors.AllowedOrigins {
+			if originAllowed(allowed, origin) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// originAllowed matches origin against "*", an exact origin or a pattern
+// with one "*" wildcard.
+func originAllowed(pattern, origin string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(pattern), "/"))
+	origin = strings.ToLower(origin)
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	return ok && len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// writeUpgradeError renders a failed handshake as an error envelope.
+func writeUpgradeError(w http.ResponseWriter, r *http.Request, status int, reason error) {
+	var he *HTTPError
+	switch {
+	case status == http.StatusForbidden:
+		he = &HTTPError{Status: status, Code: "FORBIDDEN", Type: "INVALID_ORIGIN", MessageKey: "locale.forbidden", Err: reason}
+	case status >= 500:
+		he = &HTTPError{Status: status, Code: "INTERNAL_ERROR", Type: "SERVER_ERROR", MessageKey: "locale.internal_error", Err: reason}
+	default:
+		he = &HTTPError{
+			Status:     status,
+			Code:       "BAD_REQUEST",
+			Type:       "INVALID_WEBSOCKET_HANDSHAKE",
+			MessageKey: "locale.bad_request",
+			Meta:       map[string]interface{}{"reason": reason.Error()},
+			Err:        reason,
+		}
+	}
+	handleError(w, r, he)
+}
+
+// WebSocketConn is an upgraded WebSocket connection. Writes may be issued
+// from several goroutines; reads must come from a single one, normally
+// the handler's.
+type WebSocketConn struct {
+	conn    *websocket.Conn
+	req     *Request
+	opts    WebSocketOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	closers []func()
+}
+
+func newWebSocketConn(conn *websocket.Conn, r *http.Request, o WebSocketOptions) *WebSocketConn {
+	ctx, cancel := context.WithCancel(r.Context())
+	c := &WebSocketConn{conn: conn, req: NewRequest(r.WithContext(ctx)), opts: o, ctx: ctx, cancel: cancel}
+	conn.SetReadLimit(o.ReadLimit)
+	if o.PongWait > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(o.PongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(o.PongWait))
+		})
+	}
+	if o.PingInterval > 0 {
+		go c.keepalive()
+	}
+	return c
+}
+
+// keepalive pings the peer until the connection is done.
+func (c *WebSocketConn) keepalive() {
+	t := time.NewTicker(c.opts.PingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-t.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.opts.WriteWait)); err != nil {
+				c.cancel()
+				return
+			}
+		}
+	}
+}
+
+// finish closes the connection after the handler returned err.
+func (c *WebSocketConn) finish(err error) {
+	c.cancel()
+	code, reason := websocket.CloseNormalClosure, ""
+	if err != nil && !IsWebSocketClosed(err) {
+		logger.Error("HTTP.WebSocket", logger.Fields{"path": c.req.R.URL.Path, "error": err.Error()})
+		code, reason = websocket.CloseInternalServerErr, "internal error"
+	}
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(c.opts.WriteWait))
+	_ = c.conn.Close()
+
+	c.mu.Lock()
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+	for _, fn := range closers {
+		fn()
+	}
+}
+
+// Request returns the upgraded request. Its context is canceled when the
+// connection ends.
+func (c *WebSocketConn) Request() *Request { return c.req }
+
+// Context is canceled once the connection is closed or found dead.
+func (c *WebSocketConn) Context() context.Context { return c.ctx }
+
+// Subprotocol returns the negotiated subprotocol, if any.
+func (c *WebSocketConn) Subprotocol() string { return c.conn.Subprotocol() }
+
+// Conn exposes the underlying gorilla/websocket connection.
+func (c *WebSocketConn) Conn() *websocket.Conn { return c.conn }
+
+// OnClose registers fn to run after the connection is closed.
+func (c *WebSocketConn) OnClose(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closers = append(c.closers, fn)
+}
+
+// ReadMessage reads the next message and returns its type (TextMessage or
+// BinaryMessage) and payload.
+func (c *WebSocketConn) ReadMessage() (int, []byte, error) {
+	t, b, err := c.conn.ReadMessage()
+	if err != nil {
+		c.cancel()
+	}
+	return t, b, err
+}
+
+// ReadJSON reads the next message and decodes it into v.
+func (c *WebSocketConn) ReadJSON(v interface{}) error {
+	_, b, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// WriteMessage sends one message of the given type.
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.opts.WriteWait)); err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// WriteJSON sends v encoded as a JSON text message.
+func (c *WebSocketConn) WriteJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, b)
+}
+
+// Close sends a close frame with code (for example 1008 for a policy
+// violation) and reason. The handler should return afterwards.
+func (c *WebSocketConn) Close(code int, reason string) error {
+	return c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(c.opts.WriteWait))
+}
+
+// IsWebSocketClosed reports whether err means the peer closed the
+// connection, stopped answering or sent an oversized message, as opposed
+// to a server failure.
+func IsWebSocketClosed(err error) bool {
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) || errors.Is(err, net.ErrClosed) || errors.Is(err, websocket.ErrCloseSent) || errors.Is(err, websocket.ErrReadLimit) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// Hub groups WebSocket connections into named rooms for broadcasting.
+// Connections leave their rooms automatically when they close.
+type Hub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[*WebSocketConn]struct{}
+}
+
+// NewHub returns an empty hub.
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]map[*WebSocketConn]struct{})}
+}
+
+// Join adds c to room.
+func (h *Hub) Join(room string, c *WebSocketConn) {
+	h.mu.Lock()
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[*WebSocketConn]struct{})
+		h.rooms[room] = members
+	}
+	_, joined := members[c]
+	members[c] = struct{}{}
+	h.mu.Unlock()
+	if !joined {
+		c.OnClose(func() { h.Leave(room, c) })
+	}
+}
+
+// Leave removes c from room.
+func (h *Hub) Leave(room string, c *WebSocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if members, ok := h.rooms[room]; ok {
+		delete(members, c)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Count returns the number of connections in room.
+func (h *Hub) Count(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast sends a message to every connection in room, concurrently,
+// and returns once all writes finished. Connections failing the write are
+// closed.
+func (h *Hub) Broadcast(room string, messageType int, data []byte) {
+	h.mu.RLock()
+	members := make([]*WebSocketConn, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		members = append(members, c)
+	}
+	h.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, c := range members {
+		wg.Add(1)
+		go func(c *WebSocketConn) {
+			defer wg.Done()
+			if err := c.WriteMessage(messageType, data); err != nil {
+				c.cancel()
+				_ = c.conn.Close()
+			}
+		}(c)
+	}
+	wg.Wait()
+}
+
+// BroadcastJSON sends v encoded as a JSON text message to every
+// connection in room.
+func (h *Hub) BroadcastJSON(room string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(room, TextMessage, b)
+	return nil
+}
@@ -0,0 +1,165 @@
+package kyugo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	cfg "github.com/go-kyugo/kyugo/config"
+)
+
+func wsURL(srv *httptest.Server, p string) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + p
+}
+
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial %s: %v (status %d)", url, err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	rt := NewRouter()
+	rt.WebSocket("/echo/{room}", func(conn *WebSocketConn, req *Request) error {
+		var msg map[string]string
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		msg["room"] = req.Param("room")
+		return conn.WriteJSON(msg)
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	conn := dial(t, wsURL(srv, "/echo/lobby"), nil)
+	if err := conn.WriteJSON(map[string]string{"text": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	if err := conn.ReadJSON(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "hi" || got["room"] != "lobby" {
+		t.Fatalf("echo = %v", got)
+	}
+}
+
+func TestWebSocketOriginCheck(t *testing.T) {
+	rt := NewRouter().CorsConfig(cfg.CorsConfig{AllowedOrigins: []string{"https://*.example.com"}})
+	rt.WebSocket("/ws", func(conn *WebSocketConn, req *Request) error { return nil })
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	for origin, ok := range map[string]bool{
+		"":                        true,
+		srv.URL:                   true,
+		"https://app.example.com": true,
+		"https://evil.test":       false,
+	} {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL(srv, "/ws"), header)
+		if conn != nil {
+			conn.Close()
+		}
+		if ok && err != nil {
+			t.Errorf("origin %q rejected: %v", origin, err)
+		}
+		if !ok && (err == nil || resp == nil || resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("origin %q: err %v, want a 403", origin, err)
+		}
+	}
+}
+
+func TestWebSocketRejectsPlainRequests(t *testing.T) {
+	rt := NewRouter()
+	rt.WebSocket("/ws", func(conn *WebSocketConn, req *Request) error { return nil })
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_WEBSOCKET_HANDSHAKE") {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWebSocketReadLimit(t *testing.T) {
+	rt := NewRouter()
+	rt.WebSocket("/ws", func(conn *WebSocketConn, req *Request) error {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return err
+			}
+		}
+	}, WebSocketOptions{ReadLimit: 16})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	conn := dial(t, wsURL(srv, "/ws"), nil)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 64))); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) || ce.Code != websocket.CloseMessageTooBig {
+		t.Fatalf("read after oversized message: %v, want close 1009", err)
+	}
+}
+
+func TestHubBroadcast(t *testing.T) {
+	hub := NewHub()
+	joined := make(chan struct{}, 2)
+	rt := NewRouter()
+	rt.WebSocket("/rooms/{room}", func(conn *WebSocketConn, req *Request) error {
+		hub.Join(req.Param("room"), conn)
+		joined <- struct{}{}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return err
+			}
+		}
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	a := dial(t, wsURL(srv, "/rooms/a"), nil)
+	b := dial(t, wsURL(srv, "/rooms/b"), nil)
+	<-joined
+	<-joined
+
+	if err := hub.BroadcastJSON("a", map[string]string{"msg": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	_ = a.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := a.ReadJSON(&got); err != nil || got["msg"] != "hello" {
+		t.Fatalf("room a got %v, %v", got, err)
+	}
+	_ = b.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := b.ReadMessage(); err == nil {
+		t.Fatal("room b received room a's broadcast")
+	}
+
+	a.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Count("a") != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := hub.Count("a"); n != 0 {
+		t.Fatalf("closed connection still in room: %d", n)
+	}
+}